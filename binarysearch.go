@@ -0,0 +1,182 @@
+package reversesearch
+
+/* The binary search functions are contained in this file:
+- probeLogEntry
+- seekFromTime
+- BinarySearch (exported)
+
+BinarySearch bisects the log file on byte offsets to find the first log entry
+that satisfies FromTime, and then hands over to forwardSearch (see
+forwardsearch.go) to scan the log entries inside the time range.
+*/
+
+import (
+	"io"
+	"regexp"
+	"time"
+)
+
+// probeLogEntry finds the first log entry in file that starts at or after
+// offset. If offset is not at the beginning of a line, the rest of that line is
+// skipped, as are any lines that don't match leStartRegexp (i.e. the remaining
+// lines of a multi-line log entry that offset landed inside of). The return
+// values are:
+// 1) leOffset (int64): the position in the file at which the log entry starts
+// 2) found (bool): indicates if a log entry was found before the end of the file
+// 3) fromTimeSatisfied (bool): indicates if the log entry satisfies fromTime
+// 4) err (error)
+func probeLogEntry(file io.ReaderAt, fileSize int64, offset int64,
+	leStartRegexp *regexp.Regexp, timeParser *timeParser, fromTime time.Time,
+	opts *Options) (int64, bool, bool, error) {
+
+	// a line starts at offset if offset is 0 or the previous byte is a newline,
+	// so begin reading from the previous byte and skip the line it belongs to
+	bufOffset := offset
+	skipping := false
+	if offset > 0 {
+		bufOffset = offset - 1
+		skipping = true
+	}
+
+	var bufLen int
+	if int64(opts.StartBufLen) > fileSize-bufOffset {
+		bufLen = int(fileSize - bufOffset)
+	} else {
+		bufLen = opts.StartBufLen
+	}
+	pooledBuf := opts.getBuf(bufLen)
+	defer opts.putBuf(pooledBuf)
+	buf := pooledBuf
+
+	var n int
+	var lineStart int
+	var err error
+
+	for {
+		lineEnd, nextLineStart, found := nextLine(buf, lineStart, n, opts.Newlines)
+		eof := bufOffset+int64(n) >= fileSize
+
+		if !found && !eof {
+			if skipping {
+				// the bytes of a skipped line don't need to be kept
+				bufOffset += int64(n)
+				n = 0
+				lineStart = 0
+			} else if lineStart == 0 && n == len(buf) {
+				// the line is longer than buf, so increase its length
+				buf, err = growBuf(buf, opts.MaxBufLen)
+				if err != nil {
+					return 0, false, false, err
+				}
+			} else if lineStart > 0 {
+				// shift the current line to the beginning of buf
+				copy(buf, buf[lineStart:n])
+				n -= lineStart
+				bufOffset += int64(lineStart)
+				lineStart = 0
+			}
+
+			// read as many bytes from the file as will fit in buf
+			readLen := len(buf) - n
+			if remaining := fileSize - bufOffset - int64(n); int64(readLen) > remaining {
+				readLen = int(remaining)
+			}
+			err = readAt(file, buf[n:n+readLen], bufOffset+int64(n))
+			if err != nil {
+				return 0, false, false, err
+			}
+			n += readLen
+			continue
+		}
+
+		if skipping {
+			skipping = false
+		} else {
+			// log entries whose time stamps can't be parsed are passed over (unless
+			// opts.BadTimes is BadTimeAbort), as there's no time to bisect on
+			startOfLe, fromTimeSatisfied, _, _, err := processLine(buf[lineStart:lineEnd],
+				bufOffset+int64(lineStart), leStartRegexp, timeParser, fromTime, time.Time{},
+				&badTimeState{policy: opts.BadTimes})
+			if err == errSkipLogEntry {
+				startOfLe, err = false, nil
+			}
+			if err != nil {
+				return 0, false, false, err
+			}
+			if startOfLe {
+				return bufOffset + int64(lineStart), true, fromTimeSatisfied, nil
+			}
+		}
+
+		if !found { // last line in the file
+			return 0, false, false, nil
+		}
+		lineStart = nextLineStart
+	}
+}
+
+// seekFromTime bisects file on byte offsets to find the position of the first
+// log entry that satisfies fromTime, on the assumption that log entries are
+// logged in chronological order. At each probe the position is resynced to the
+// start of the next log entry with probeLogEntry. fileSize is returned if no
+// log entry in the file satisfies fromTime.
+func seekFromTime(file io.ReaderAt, fileSize int64, leStartRegexp *regexp.Regexp,
+	timeParser *timeParser, fromTime time.Time, opts *Options) (int64, error) {
+
+	// the first log entry that satisfies fromTime is the first log entry found
+	// at or after some offset in [lo, hi]
+	lo, hi := int64(0), fileSize
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		leOffset, found, fromTimeSatisfied, err := probeLogEntry(file, fileSize, mid,
+			leStartRegexp, timeParser, fromTime, opts)
+		if err != nil {
+			return 0, err
+		}
+
+		if found && !fromTimeSatisfied {
+			// neither this log entry nor any of the ones before it can satisfy fromTime
+			lo = leOffset + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	leOffset, found, _, err := probeLogEntry(file, fileSize, lo, leStartRegexp,
+		timeParser, fromTime, opts)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return fileSize, nil
+	}
+	return leOffset, nil
+}
+
+// BinarySearch searches the log file specified by filePath for matching log
+// entries within a time range. Rather than traversing the whole file, it bisects
+// the file to find the first log entry that satisfies searchCriteria.FromTime,
+// and then searches forwards from that log entry until the first log entry that
+// fails searchCriteria.UntilTime (or until the end of the file if UntilTime is
+// not set). This makes it the most performant search function when the time
+// range is far from both the beginning and end of a large log file. Matching
+// log entries are passed to outputHandler in the order they appear in the file.
+//
+// searchCriteria.FromTime must be set, and as with ReverseSearch, log entries
+// are assumed to be in chronological order. Since the beginning of the file is
+// only read if the time range starts there, bytes before the first log entry
+// are not reported as an error. Return values are the same as those of
+// ReverseSearch:
+//
+// 1) exitStatus (int): -1 indicates an error was found, 0 indicates normal
+// execution without issues, 1 indicates file is empty (not considered an error)
+//
+// 2) err (error)
+func BinarySearch(filePath string, searchCriteria *SearchCriteria,
+	outputHandler OutputHandler) (int, error) {
+	searcher, err := NewSearcher(searchCriteria, nil)
+	if err != nil {
+		return -1, err
+	}
+	return searcher.BinarySearch(filePath, outputEntryHandler(outputHandler))
+}
@@ -0,0 +1,208 @@
+package reversesearch_test
+
+/* Integration tests for BinarySearch. As with the ForwardSearch tests, the
+green path tests compare BinarySearch's matches with those of ForwardSearch
+rather than keeping a separate set of expected output files. */
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/freebiesoft/reversesearch"
+)
+
+// Green path testing of BinarySearch function in reversesearch package
+func TestGreenPathsBinarySearch(t *testing.T) {
+	StartBufLen = 256
+	origStartBufLen := StartBufLen
+
+	var tests = []struct {
+		name           string         // test name (also description summary)
+		filePath       string         // path of file to be searched
+		searchCriteria SearchCriteria // second parameter of BinarySearch
+		setUp          func()         // set up actions
+		cleanUp        func()         // clean up actions
+	}{
+		{
+			name:     "test 1: time range in the middle of a large file",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				Regexps:        []string{`/modules/mod_araticlhess1/mod_araticlhess1\.php`},
+				FromTime:       parseTime(apacheTimeFormat, `23/Sep/2019:00:00:00 +0200`),
+				UntilTime:      parseTime(apacheTimeFormat, `23/Sep/2019:00:30:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+		},
+		{
+			name:     "test 2: time range starts before first log entry",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(apacheTimeFormat, `01/Jan/2019:00:00:00 +0200`),
+				UntilTime:      parseTime(apacheTimeFormat, `21/Sep/2019:21:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+		},
+		{
+			name:     "test 3: time range ends after last log entry",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(apacheTimeFormat, `23/Sep/2019:16:00:00 +0200`),
+				UntilTime:      parseTime(apacheTimeFormat, `01/Jan/2020:00:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+		},
+		{
+			name:     "test 4: time range after last log entry",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(apacheTimeFormat, `01/Jan/2020:00:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+		},
+		{
+			name:     "test 5: multiline log entries, probes land inside large entries",
+			filePath: odlLog,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(odlTimeFormat, `Jun 15, 2010 2:02:02 AM IST`),
+				UntilTime:      parseTime(odlTimeFormat, `Jun 17, 2010 11:30:00 PM IST`),
+				LeStartPattern: odlStartPattern,
+				LeTimeFormat:   odlTimeFormat,
+			},
+			setUp:   func() { StartBufLen = 8 },
+			cleanUp: func() { StartBufLen = origStartBufLen },
+		},
+		{
+			name:     `test 6: \r\n prefix`,
+			filePath: accessLogNlPrefixWin,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(apacheTimeFormat, `01/Jan/2019:00:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+			setUp:   func() { StartBufLen = 1 },
+			cleanUp: func() { StartBufLen = origStartBufLen },
+		},
+		{
+			name:     "test 7: entry larger than StartBufLen",
+			filePath: oneLargeOneSmall,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(odlTimeFormat, `Jun 15, 2010 2:01:21 AM IST`),
+				LeStartPattern: odlStartPattern,
+				LeTimeFormat:   odlTimeFormat,
+			},
+			setUp:   func() { StartBufLen = 1 },
+			cleanUp: func() { StartBufLen = origStartBufLen },
+		},
+		{
+			name:     "test 8: time range between two log entries",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(apacheTimeFormat, `21/Sep/2019:22:00:00 +0200`),
+				UntilTime:      parseTime(apacheTimeFormat, `21/Sep/2019:23:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.setUp != nil {
+				test.setUp()
+			}
+			if test.cleanUp != nil {
+				defer test.cleanUp()
+			}
+
+			var expected []string
+			_, err := ForwardSearch(test.filePath, &test.searchCriteria,
+				func(logEntry []byte) { expected = append(expected, string(logEntry)) })
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			var actual []string
+			exitStatus, err := BinarySearch(test.filePath, &test.searchCriteria,
+				func(logEntry []byte) { actual = append(actual, string(logEntry)) })
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("BinarySearch matches do not match ForwardSearch matches."+
+					"\nGOT:\n%s\nWANT:\n%s", strings.Join(actual, "\n"),
+					strings.Join(expected, "\n"))
+			}
+			if exitStatus != 0 {
+				t.Errorf("Got exit status: %d, want exit status: 0", exitStatus)
+			}
+		})
+	}
+}
+
+// Red path testing of BinarySearch
+func TestRedPathsBinarySearch(t *testing.T) {
+	StartBufLen = 256
+
+	var tests = []struct {
+		name           string         // test name (also description summary)
+		filePath       string         // path to log file
+		searchCriteria SearchCriteria // searchCriteria ; 2nd param of BinarySearch
+		expectedErr    string         // string that should be contained within the error obj
+	}{
+		{
+			name:     "test 1: fromTime must be set",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				UntilTime:      parseTime(apacheTimeFormat, `23/Sep/2019:00:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+			expectedErr: NoFromTime,
+		},
+		{
+			name:     "test 2: leTimeFormat mismatch",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(apacheTimeFormat, `23/Sep/2019:00:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat + `unmatch`,
+			},
+			expectedErr: LeTimeFormatMismatch,
+		},
+		{
+			name:     "test 3: fromTime after untilTime",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(apacheTimeFormat, `23/Sep/2019:10:00:00 +0200`),
+				UntilTime:      parseTime(apacheTimeFormat, `22/Sep/2019:23:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+			expectedErr: FromTimeAfterUntilTime,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exitStatus, err := BinarySearch(test.filePath, &test.searchCriteria,
+				func(logEntry []byte) {})
+			if err == nil {
+				t.Error("No error returned")
+			} else if !strings.Contains(err.Error(), test.expectedErr) {
+				t.Errorf("Got error: \"%s\", want error that contains: \"%s\"",
+					err.Error(), test.expectedErr)
+			}
+			if exitStatus != -1 {
+				t.Errorf("Got exit status: %d, want exit status: -1", exitStatus)
+			}
+		})
+	}
+}
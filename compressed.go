@@ -0,0 +1,162 @@
+package reversesearch
+
+/* The compressed log file functions are contained in this file:
+- isBzip2
+- compressionFormat
+- decompress
+- openLogFile
+
+The search functions need random access to the bytes of a log file (i.e.
+ReadAt), which compressed streams don't offer. Compressed log files are
+therefore decompressed, one bounded chunk at a time, into a temporary file
+which is searched in place of the compressed file and then removed.
+*/
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// TempDir is the directory in which compressed log files are decompressed
+// before being searched. When empty, the default directory for temporary
+// files is used (see os.TempDir).
+var TempDir = ""
+
+// magic numbers that identify compressed files
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+
+	// a bzip2 stream's magic number is followed by its block size ('1'-'9'), and
+	// then either the magic number of its first block, or that of the end of the
+	// stream if it's empty. These are checked too, as "BZh" alone could well be
+	// the start of a plain text log file.
+	bzip2BlockMagic = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	bzip2EndMagic   = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+)
+
+// isBzip2 reports whether magic, the first bytes of a file, are the start of a
+// bzip2 stream
+func isBzip2(magic []byte) bool {
+	headerLen := len(bzip2Magic) + 1
+	if len(magic) < headerLen+len(bzip2BlockMagic) || !bytes.HasPrefix(magic, bzip2Magic) ||
+		magic[len(bzip2Magic)] < '1' || magic[len(bzip2Magic)] > '9' {
+		return false
+	}
+	return bytes.HasPrefix(magic[headerLen:], bzip2BlockMagic) ||
+		bytes.HasPrefix(magic[headerLen:], bzip2EndMagic)
+}
+
+// compressionFormat identifies the compression format of file from its magic
+// number, returning one of the compressionExts, or "" if file is not compressed
+func compressionFormat(file *os.File) (string, error) {
+	magic := make([]byte, len(bzip2Magic)+1+len(bzip2BlockMagic))
+	n, err := file.ReadAt(magic, 0)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	magic = magic[:n]
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return ".gz", nil
+	case isBzip2(magic):
+		return ".bz2", nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		return ".zst", nil
+	case bytes.HasPrefix(magic, xzMagic):
+		return ".xz", nil
+	}
+	return "", nil
+}
+
+// decompress writes the decompressed contents of file, which is compressed
+// with the format identified by ext, to dst, one bufLen sized chunk at a time
+func decompress(dst io.Writer, file *os.File, ext string, bufLen int) error {
+	src := io.NewSectionReader(file, 0, 1<<63-1)
+
+	var r io.Reader
+	switch ext {
+	case ".gz":
+		gzipReader, err := gzip.NewReader(src)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		r = gzipReader
+	case ".bz2":
+		r = bzip2.NewReader(src)
+	case ".zst":
+		zstdReader, err := zstd.NewReader(src, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return err
+		}
+		defer zstdReader.Close()
+		r = zstdReader
+	default:
+		return fmt.Errorf("%w (%s)", ErrUnsupportedCompression, ext)
+	}
+
+	// copy the decompressed bytes one chunk at a time
+	if bufLen < 1 {
+		bufLen = 1
+	}
+	_, err := io.CopyBuffer(dst, r, make([]byte, bufLen))
+	return err
+}
+
+// openLogFile opens the log file at filePath for searching. If the file has
+// been compressed with gzip, bzip2 or zstd, the file returned is a temporary
+// file holding the decompressed log file. The function returned closes the
+// file (and removes it if it's a temporary file), and should be called once the
+// search is complete. The file is decompressed one opts.StartBufLen sized chunk
+// at a time.
+func openLogFile(filePath string, opts *Options) (*os.File, func() error, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ext, err := compressionFormat(file)
+	if err != nil || ext == "" {
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		return file, file.Close, nil
+	}
+	defer file.Close()
+
+	tempFile, err := ioutil.TempFile(TempDir, "reversesearch-*.log")
+	if err != nil {
+		return nil, nil, err
+	}
+	closeTempFile := func() error {
+		err := tempFile.Close()
+		if removeErr := os.Remove(tempFile.Name()); err == nil {
+			err = removeErr
+		}
+		return err
+	}
+
+	if err := decompress(tempFile, file, ext, opts.StartBufLen); err != nil {
+		closeTempFile()
+		return nil, nil, err
+	}
+
+	// the decompressed log file keeps the compressed log file's modification time,
+	// as year-less time stamps are anchored on it (see Options.YearReference)
+	if fileInfo, err := file.Stat(); err == nil {
+		os.Chtimes(tempFile.Name(), fileInfo.ModTime(), fileInfo.ModTime())
+	}
+	return tempFile, closeTempFile, nil
+}
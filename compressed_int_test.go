@@ -0,0 +1,151 @@
+package reversesearch_test
+
+/* Integration tests for searching compressed log files. The compressed test
+files are access.log compressed with gzip, bzip2, zstd and xz, so the results
+of searching them are compared with the results of searching access.log. */
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/freebiesoft/reversesearch"
+)
+
+var accessLogGzip = logsDir + `access.log.gz`
+var accessLogBzip2 = logsDir + `access.log.bz2`
+var accessLogZstd = logsDir + `access.log.zst`
+var accessLogXz = logsDir + `access.log.xz`
+
+// TestGreenPathsCompressed checks that compressed log files are searched as
+// though they were uncompressed
+func TestGreenPathsCompressed(t *testing.T) {
+	StartBufLen = 256
+
+	searchCriteria := SearchCriteria{
+		Regexps:        []string{`/modules/mod_araticlhess1/mod_araticlhess1\.php`},
+		FromTime:       parseTime(apacheTimeFormat, `21/Sep/2019:00:00:00 +0200`),
+		UntilTime:      parseTime(apacheTimeFormat, `24/Sep/2019:00:00:00 +0200`),
+		LeStartPattern: apacheStartPattern,
+		LeTimeFormat:   apacheTimeFormat,
+	}
+
+	var searchFuncs = []struct {
+		name   string
+		search func(string, *SearchCriteria, OutputHandler) (int, error)
+	}{
+		{"ReverseSearch", ReverseSearch},
+		{"ForwardSearch", ForwardSearch},
+		{"BinarySearch", BinarySearch},
+	}
+
+	for _, searchFunc := range searchFuncs {
+		var expected []string
+		_, err := searchFunc.search(accessLog, &searchCriteria,
+			func(logEntry []byte) { expected = append(expected, string(logEntry)) })
+		check(err)
+		if len(expected) == 0 {
+			t.Fatal("no matching log entries found in " + accessLog)
+		}
+
+		for _, filePath := range []string{accessLogGzip, accessLogBzip2, accessLogZstd} {
+			t.Run(searchFunc.name+" "+filepath.Base(filePath), func(t *testing.T) {
+				var actual []string
+				exitStatus, err := searchFunc.search(filePath, &searchCriteria,
+					func(logEntry []byte) { actual = append(actual, string(logEntry)) })
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(actual, expected) {
+					t.Errorf("Got %d matches, want %d matches", len(actual), len(expected))
+				}
+				if exitStatus != 0 {
+					t.Errorf("Got exit status: %d, want exit status: 0", exitStatus)
+				}
+			})
+		}
+	}
+}
+
+// TestCompressedTempFileRemoved checks that the temporary file a compressed
+// log file is decompressed into is removed once the search is complete
+func TestCompressedTempFileRemoved(t *testing.T) {
+	TempDir = t.TempDir()
+	defer func() { TempDir = "" }()
+
+	_, err := ReverseSearch(accessLogGzip, &SearchCriteria{LeStartPattern: apacheStartPattern},
+		func([]byte) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := ioutil.ReadDir(TempDir)
+	check(err)
+	if len(files) != 0 {
+		t.Errorf("Got %d files left in TempDir, want 0", len(files))
+	}
+}
+
+// TestPlainLogLikeBzip2 checks that a plain log file whose first bytes happen
+// to be those of bzip2's magic number isn't mistaken for a compressed file
+func TestPlainLogLikeBzip2(t *testing.T) {
+	StartBufLen = 256
+
+	for _, contents := range []string{
+		"BZh started\nBZh stopped\n",
+		"BZh9 started\nBZh9 stopped\n",
+	} {
+		t.Run(strings.Fields(contents)[0], func(t *testing.T) {
+			logFile := filepath.Join(t.TempDir(), "plain.log")
+			check(ioutil.WriteFile(logFile, []byte(contents), 0644))
+
+			var actual []string
+			exitStatus, err := ReverseSearch(logFile, &SearchCriteria{LeStartPattern: `^BZh`},
+				func(logEntry []byte) { actual = append(actual, string(logEntry)) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := strings.Split(strings.TrimSuffix(contents, "\n"), "\n")
+			expected[0], expected[1] = expected[1], expected[0]
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("GOT:\n%q\nWANT:\n%q", actual, expected)
+			}
+			if exitStatus != 0 {
+				t.Errorf("Got exit status: %d, want exit status: 0", exitStatus)
+			}
+		})
+	}
+}
+
+// TestRedPathsCompressed checks the errors returned for compressed log files
+// that cannot be decompressed
+func TestRedPathsCompressed(t *testing.T) {
+	// a truncated gzip file
+	contents, err := ioutil.ReadFile(accessLogGzip)
+	check(err)
+	truncatedGzip := filepath.Join(t.TempDir(), "access.log.gz")
+	check(ioutil.WriteFile(truncatedGzip, contents[:len(contents)/2], 0644))
+
+	var tests = []struct {
+		name     string
+		filePath string
+		expected string
+	}{
+		{"test 1: xz is not supported", accessLogXz, UnsupportedCompression},
+		{"test 2: truncated gzip file", truncatedGzip, "unexpected EOF"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exitStatus, err := ReverseSearch(test.filePath,
+				&SearchCriteria{LeStartPattern: apacheStartPattern}, func([]byte) {})
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Got error: %v, want error that contains: \"%s\"", err, test.expected)
+			}
+			if exitStatus != -1 {
+				t.Errorf("Got exit status: %d, want exit status: -1", exitStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package reversesearch
+
+/* The iterator functions are contained in this file:
+- Entries (exported)
+
+Entries offers a pull based alternative to passing a handler to ReverseSearch,
+i.e. for logEntry, err := range Entries(filePath, searchCriteria) { ... }
+*/
+
+import (
+	"context"
+	"iter"
+)
+
+// Entries returns an iterator over the log entries in the log file specified
+// by filePath that match searchCriteria, in the order ReverseSearch finds them
+// (i.e. most recent first). The log file is only searched while the iterator is
+// being ranged over, and breaking out of the loop ends the search immediately,
+// so no further bytes are read from the file. If the search fails, the error is
+// yielded (with a zero LogEntry) as the last iteration. An empty file yields no
+// iterations.
+//
+// As with an EntryHandler, logEntry.Bytes refers to the search's buffer, so it
+// is only valid until the next iteration; it must be copied if it's needed
+// afterwards.
+func Entries(filePath string, searchCriteria *SearchCriteria) iter.Seq2[LogEntry, error] {
+	return func(yield func(LogEntry, error) bool) {
+		stopped := false
+		_, err := ReverseSearchLogEntries(context.Background(), filePath, searchCriteria,
+			func(logEntry LogEntry) (Control, error) {
+				if !yield(logEntry, nil) {
+					stopped = true
+					return Stop, nil
+				}
+				return Continue, nil
+			})
+		if err != nil && !stopped {
+			yield(LogEntry{}, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package reversesearch_test
+
+/* Integration tests for Entries; the log entries it yields are compared with
+the log entries ReverseSearch passes to its output handler. */
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/freebiesoft/reversesearch"
+)
+
+// TestEntries checks that Entries yields the same log entries as ReverseSearch
+func TestEntries(t *testing.T) {
+	StartBufLen = 256
+
+	var tests = []struct {
+		name           string
+		filePath       string
+		searchCriteria SearchCriteria
+	}{
+		{
+			name:     "test 1: regexp match",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				Regexps:        []string{`/modules/mod_araticlhess1/mod_araticlhess1\.php`},
+				LeStartPattern: apacheStartPattern,
+			},
+		},
+		{
+			name:     "test 2: fromTime constraint, multi line log entries",
+			filePath: odlLog,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(odlTimeFormat, `Jun 16, 2010 12:00:00 AM IST`),
+				LeStartPattern: odlStartPattern,
+				LeTimeFormat:   odlTimeFormat,
+			},
+		},
+		{
+			name:           "test 3: empty file",
+			filePath:       emptyFile,
+			searchCriteria: SearchCriteria{LeStartPattern: odlStartPattern},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var expected []string
+			_, err := ReverseSearch(test.filePath, &test.searchCriteria,
+				func(logEntry []byte) { expected = append(expected, string(logEntry)) })
+			check(err)
+
+			var actual []string
+			for logEntry, err := range Entries(test.filePath, &test.searchCriteria) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				actual = append(actual, string(logEntry.Bytes))
+			}
+			if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+				t.Errorf("Got %d log entries, want %d log entries", len(actual), len(expected))
+			}
+		})
+	}
+}
+
+// TestEntriesBreak checks that breaking out of the loop ends the search, and
+// that errors are yielded
+func TestEntriesBreak(t *testing.T) {
+	StartBufLen = 256
+	searchCriteria := SearchCriteria{LeStartPattern: apacheStartPattern}
+
+	// access_no_more_entries.log begins with lines that aren't part of a log
+	// entry, which the search only discovers if it reaches the beginning of the
+	// file. Breaking out of the loop means it doesn't (and yielding after the
+	// loop has been broken out of would cause a panic).
+	count := 0
+	for _, err := range Entries(accessLogNoMoreEntries, &searchCriteria) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+		if count == 10 {
+			break
+		}
+	}
+	if count != 10 {
+		t.Errorf("Got %d log entries, want 10", count)
+	}
+
+	var lastErr error
+	for _, err := range Entries(accessLogNoMoreEntries, &searchCriteria) {
+		lastErr = err
+	}
+	if lastErr == nil || !strings.Contains(lastErr.Error(), NoMoreLogEntries) {
+		t.Errorf("Got error: %v, want error that contains: \"%s\"", lastErr, NoMoreLogEntries)
+	}
+}
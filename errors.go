@@ -0,0 +1,121 @@
+package reversesearch
+
+/* The error values and types returned by the search functions are contained in
+this file:
+- sentinel errors (e.g. ErrNoMoreLogEntries)
+- NoMoreEntriesError (type)
+- TimeParseError (type)
+- CanceledError (type)
+
+Each sentinel error's message is the corresponding string constant in
+errors_unix.go and errors_windows.go. Errors that carry extra detail (e.g. an
+offset) either wrap a sentinel error or match it via an Is method, so callers
+can use errors.Is and errors.As rather than comparing err.Error() text. A non
+existent log file is reported with the error returned by os.Open, which matches
+fs.ErrNotExist on every platform.
+*/
+
+import (
+	"errors"
+	"strconv"
+)
+
+// sentinel errors, for use with errors.Is
+var (
+	ErrNoLogEntriesInFile        = errors.New(NoLogEntriesInFile)
+	ErrNoMoreLogEntries          = errors.New(NoMoreLogEntries)
+	ErrFileIsEmpty               = errors.New(FileIsEmpty)
+	ErrNoLeTimeFormat            = errors.New(NoLeTimeFormat)
+	ErrNoLeStartPattern          = errors.New(NoLeStartPattern)
+	ErrMultipleLeStartPatterns   = errors.New(MultipleLeStartPatterns)
+	ErrNoFromTime                = errors.New(NoFromTime)
+	ErrFromTimeAfterUntilTime    = errors.New(FromTimeAfterUntilTime)
+	ErrNegativeTolerance         = errors.New(NegativeTolerance)
+	ErrTimeZoneMismatch          = errors.New(TimeZoneMismatch)
+	ErrMaxBufLenReached          = errors.New(MaxBufLenReached)
+	ErrLeTimeFormatMismatch      = errors.New(LeTimeFormatMismatch)
+	ErrBufOffsetLessThanZero     = errors.New(BufOffsetLessThanZero)
+	ErrLeStartPatternBadlyFormed = errors.New(LeStartPatternBadlyFormed)
+	ErrBadLeStartPattern         = errors.New(BadLeStartPattern)
+	ErrBadRegexps                = errors.New(BadRegexps)
+	ErrUnsupportedCompression    = errors.New(UnsupportedCompression)
+	ErrMaxEntryLenExceeded       = errors.New(MaxEntryLenExceeded)
+	ErrSearchCanceled            = errors.New(SearchCanceled)
+	ErrUnknownPreset             = errors.New(UnknownPreset)
+	ErrBadPreset                 = errors.New(BadPreset)
+)
+
+// NoMoreEntriesError is returned when LeStartPattern stops matching beyond a
+// certain point in the log file (see NoMoreLogEntries). Offset is the position
+// in the log file of the newline that precedes the first log entry found, i.e.
+// the bytes before Offset aren't part of any log entry. It matches
+// ErrNoMoreLogEntries.
+type NoMoreEntriesError struct {
+	Offset int64
+}
+
+// Error returns NoMoreLogEntries along with the offset
+func (e *NoMoreEntriesError) Error() string {
+	return NoMoreLogEntries + `, last log entry found at ` + strconv.FormatInt(e.Offset, 10)
+}
+
+// Is reports whether target is ErrNoMoreLogEntries
+func (e *NoMoreEntriesError) Is(target error) bool {
+	return target == ErrNoMoreLogEntries
+}
+
+// TimeParseError is returned when the time stamp captured by LeStartPattern
+// can't be parsed with LeTimeFormat (or any of LeTimeFormats). Bytes holds the
+// time stamp, Offset is the position in the log file of the first byte of the
+// log entry, and Err is the error returned by time.Parse for the first layout
+// tried. It matches ErrLeTimeFormatMismatch.
+type TimeParseError struct {
+	Bytes  []byte
+	Offset int64
+	Err    error
+}
+
+// Error returns LeTimeFormatMismatch along with the time stamp and offset
+func (e *TimeParseError) Error() string {
+	return LeTimeFormatMismatch + ` for time stamp "` + string(e.Bytes) +
+		`" of log entry found at ` + strconv.FormatInt(e.Offset, 10)
+}
+
+// Is reports whether target is ErrLeTimeFormatMismatch
+func (e *TimeParseError) Is(target error) bool {
+	return target == ErrLeTimeFormatMismatch
+}
+
+// Unwrap returns the error returned by time.Parse
+func (e *TimeParseError) Unwrap() error {
+	return e.Err
+}
+
+// CanceledError is returned by the context aware search functions (e.g.
+// ReverseSearchContext) when ctx is canceled, or its deadline passes, before
+// the search is complete. Offset is the byte offset in the log file that the
+// search had reached; every log entry that starts at or after Offset has
+// already been searched, so the remainder of the file is [0:Offset]. Err is the
+// error returned by ctx.Err(), so errors.Is(err, context.Canceled) and
+// errors.Is(err, context.DeadlineExceeded) work as expected. It also matches
+// ErrSearchCanceled.
+type CanceledError struct {
+	Offset int64
+	Err    error
+}
+
+// Error returns SearchCanceled along with the offset reached and ctx.Err()
+func (e *CanceledError) Error() string {
+	return SearchCanceled + " at offset " + strconv.FormatInt(e.Offset, 10) +
+		": " + e.Err.Error()
+}
+
+// Is reports whether target is ErrSearchCanceled
+func (e *CanceledError) Is(target error) bool {
+	return target == ErrSearchCanceled
+}
+
+// Unwrap returns ctx.Err()
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
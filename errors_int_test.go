@@ -0,0 +1,156 @@
+package reversesearch_test
+
+/* Integration tests for the sentinel errors and error types; the errors
+returned by the search functions are checked with errors.Is and errors.As
+rather than by comparing their text. */
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"testing"
+
+	. "github.com/freebiesoft/reversesearch"
+)
+
+// TestErrorsIs checks that the errors returned by the search functions match
+// the expected sentinel errors
+func TestErrorsIs(t *testing.T) {
+	StartBufLen = 256
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var tests = []struct {
+		name        string
+		search      func() (int, error)
+		expectedErr error
+	}{
+		{
+			name: "test 1: no more log entries",
+			search: func() (int, error) {
+				return ReverseSearch(accessLogNoMoreEntries,
+					&SearchCriteria{LeStartPattern: apacheStartPattern}, func([]byte) {})
+			},
+			expectedErr: ErrNoMoreLogEntries,
+		},
+		{
+			name: "test 2: no log entries in file",
+			search: func() (int, error) {
+				return ReverseSearch(accessLog, &SearchCriteria{LeStartPattern: odlStartPattern},
+					func([]byte) {})
+			},
+			expectedErr: ErrNoLogEntriesInFile,
+		},
+		{
+			name: "test 3: no leStartPattern",
+			search: func() (int, error) {
+				return ForwardSearch(accessLog, &SearchCriteria{}, func([]byte) {})
+			},
+			expectedErr: ErrNoLeStartPattern,
+		},
+		{
+			name: "test 4: leTimeFormat mismatch",
+			search: func() (int, error) {
+				return ReverseSearch(accessLog, &SearchCriteria{
+					FromTime:       parseTime(apacheTimeFormat, `23/Sep/2019:00:00:00 +0200`),
+					LeStartPattern: apacheStartPattern,
+					LeTimeFormat:   odlTimeFormat,
+				}, func([]byte) {})
+			},
+			expectedErr: ErrLeTimeFormatMismatch,
+		},
+		{
+			name: "test 5: MaxBufLen reached",
+			search: func() (int, error) {
+				return ReverseSearchWithOptions(context.Background(), longLineLog,
+					&SearchCriteria{LeStartPattern: odlStartPattern}, &Options{MaxBufLen: 5000},
+					func(LogEntry) (Control, error) { return Continue, nil })
+			},
+			expectedErr: ErrMaxBufLenReached,
+		},
+		{
+			name: "test 6: search canceled",
+			search: func() (int, error) {
+				return ReverseSearchContext(canceledCtx, accessLog,
+					&SearchCriteria{LeStartPattern: apacheStartPattern}, func([]byte) {})
+			},
+			expectedErr: ErrSearchCanceled,
+		},
+		{
+			name: "test 7: unsupported compression",
+			search: func() (int, error) {
+				return ReverseSearch(accessLogXz,
+					&SearchCriteria{LeStartPattern: apacheStartPattern}, func([]byte) {})
+			},
+			expectedErr: ErrUnsupportedCompression,
+		},
+		{
+			name: "test 8: file does not exist",
+			search: func() (int, error) {
+				return BinarySearch(logsDir+"does_not_exist.log", &SearchCriteria{
+					FromTime:       parseTime(apacheTimeFormat, `23/Sep/2019:00:00:00 +0200`),
+					LeStartPattern: apacheStartPattern,
+					LeTimeFormat:   apacheTimeFormat,
+				}, func([]byte) {})
+			},
+			expectedErr: fs.ErrNotExist,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exitStatus, err := test.search()
+			if exitStatus != -1 {
+				t.Errorf("Got exit status: %d, want exit status: -1", exitStatus)
+			}
+			if !errors.Is(err, test.expectedErr) {
+				t.Errorf("Got error: %v, want error that is: %v", err, test.expectedErr)
+			}
+		})
+	}
+}
+
+// TestErrorsAs checks that the structured error types hold the offsets (and
+// bytes) that the errors relate to
+func TestErrorsAs(t *testing.T) {
+	StartBufLen = 256
+
+	// the bytes before the first log entry's preceding newline aren't part of any
+	// log entry
+	_, err := ForwardSearch(accessLogNoMoreEntries,
+		&SearchCriteria{LeStartPattern: apacheStartPattern}, func([]byte) {})
+	var noMoreEntriesErr *NoMoreEntriesError
+	if !errors.As(err, &noMoreEntriesErr) {
+		t.Fatalf("Got error: %v, want *NoMoreEntriesError", err)
+	}
+	contents, err := ioutil.ReadFile(accessLogNoMoreEntries)
+	check(err)
+	firstLeOffset := int64(bytes.Index(contents, []byte("\n54.37.76.123")))
+	if noMoreEntriesErr.Offset != firstLeOffset {
+		t.Errorf("Got offset: %d, want offset: %d", noMoreEntriesErr.Offset, firstLeOffset)
+	}
+
+	// the log entry whose time stamp can't be parsed is the last one in the file
+	_, err = ReverseSearch(accessLog, &SearchCriteria{
+		FromTime:       parseTime(apacheTimeFormat, `23/Sep/2019:00:00:00 +0200`),
+		LeStartPattern: apacheStartPattern,
+		LeTimeFormat:   apacheTimeFormat + `unmatch`,
+	}, func([]byte) {})
+	var timeParseErr *TimeParseError
+	if !errors.As(err, &timeParseErr) {
+		t.Fatalf("Got error: %v, want *TimeParseError", err)
+	}
+	contents, err = ioutil.ReadFile(accessLog)
+	check(err)
+	lastLeOffset := int64(bytes.LastIndexByte(bytes.TrimSuffix(contents, []byte("\n")), '\n') + 1)
+	if timeParseErr.Offset != lastLeOffset {
+		t.Errorf("Got offset: %d, want offset: %d", timeParseErr.Offset, lastLeOffset)
+	}
+	if len(timeParseErr.Bytes) == 0 || !bytes.Contains(contents[lastLeOffset:],
+		append(append([]byte("["), timeParseErr.Bytes...), ']')) {
+		t.Errorf("Got time stamp: %q, want the last log entry's time stamp",
+			timeParseErr.Bytes)
+	}
+}
@@ -16,7 +16,8 @@ const NoLogEntriesInFile = "No log entries found in file"
 // of the file
 const NoMoreLogEntries = "No more entries found"
 
-// FileIsEmpty is returned (encapsulated in an error) when there're no characters detected in the file
+// FileIsEmpty is returned (encapsulated in an error) when there're no characters detected in the file,
+// and the EmptyFileIsError option is set
 const FileIsEmpty = "File is empty"
 
 // NoLeTimeFormat is returned (encapsulated in an error) when the user has not specified LeTimeFormat in their
@@ -26,13 +27,31 @@ const FileIsEmpty = "File is empty"
 const NoLeTimeFormat = "leTimeFormat must be set if there are time constraints"
 
 // NoLeStartPattern is returned (encapsulated in an error) when the user has not specified LeStartPattern in
-// their search criteria parameter to ReverseSearch
+// their search criteria parameter to ReverseSearch (or LeStartPatterns, or one of its patterns is empty)
 const NoLeStartPattern = "leStartPattern must be set in search criteria"
 
+// MultipleLeStartPatterns is returned (encapsulated in an error) when the user has specified both
+// LeStartPattern and LeStartPatterns in their search criteria; only one of them can be set
+const MultipleLeStartPatterns = "only one of leStartPattern and leStartPatterns can be set"
+
+// NoFromTime is returned (encapsulated in an error) when the user has not specified FromTime in their
+// search criteria parameter to BinarySearch. BinarySearch needs FromTime to know which log entry to
+// seek to within the log file.
+const NoFromTime = "fromTime must be set in search criteria for a binary search"
+
 // FromTimeAfterUntilTime is returned (encapsulated in an error) when the user has specified both FromTime
 // and UntilTime, and FromTime > UntilTime
 const FromTimeAfterUntilTime = "fromTime needs to be before untilTime"
 
+// NegativeTolerance is returned (encapsulated in an error) when the user has specified a negative
+// Tolerance in their search criteria
+const NegativeTolerance = "tolerance must not be negative"
+
+// TimeZoneMismatch is returned (encapsulated in an error) when FromTime or UntilTime is in a different
+// time zone to the one the log's time stamps are parsed in (see LeTimeLocation and LeTimeZones),
+// which would cause them to be compared hours apart
+const TimeZoneMismatch = "time constraints aren't in the same time zone as the log's time stamps"
+
 // MaxBufLenReached is returned (encapsulated in an error) when the byte buffer's size has exceded MaxBufLen.
 // This most commonly happens when the user has specified an LeStartPattern that
 // doesn't match any log entries, but can also happen when MaxBufLen is too small,
@@ -51,7 +70,8 @@ const BufOffsetLessThanZero = "Buffer offset is less than zero"
 // LeStartPatternBadlyFormed is returned (encapsulated in an error) when LeStartPattern matches the beginning
 // of a log entry, but either none or more than 1 capturing groups where found.
 // Only one capturing group should be defined within LeStartPattern which should
-// capture the timestamp of the log entry
+// capture the timestamp of the log entry, unless the timestamp is captured by a
+// capturing group named "ts" (in which case any number of other groups are allowed)
 const LeStartPatternBadlyFormed = "leStartPattern should have 1 capturing group"
 
 // BadLeStartPattern is returned (encapsulated in an error) when the search criteria's LeStartPattern field
@@ -62,6 +82,26 @@ const BadLeStartPattern = "search criteria's LeStartPattern field won't compile"
 // Regexps field won't compile (i.e. regexp.Compile returns an error)
 const BadRegexps = "one of the regex strings in search criteria's Regexps field won't compile"
 
+// UnsupportedCompression is returned (encapsulated in an error) when a log file has been compressed
+// with a compression format that cannot be decompressed, i.e. anything other than gzip, bzip2 or zstd
+const UnsupportedCompression = "log file compression format is not supported"
+
+// MaxEntryLenExceeded is returned (encapsulated in an error) when a log entry is longer than the
+// MaxEntryLen option
+const MaxEntryLenExceeded = "log entry is longer than the maximum log entry length"
+
+// SearchCanceled is returned (encapsulated in a CanceledError) when the context passed to one of the
+// context aware search functions is canceled, or its deadline passes, before the search is complete
+const SearchCanceled = "search canceled"
+
 // BadFilePath is returned (encapsulated in an error) when user specifies a non existent filePath parameter
-// to ReverseSearch. The value on this is platform dependant
+// to ReverseSearch. The value on this is platform dependant, so rather than comparing error text, use
+// errors.Is(err, fs.ErrNotExist), which works on every platform
 const BadFilePath = "no such file or directory"
+
+// UnknownPreset is returned (encapsulated in an error) when ApplyPreset is passed the name of a preset that
+// hasn't been registered
+const UnknownPreset = "no preset is registered under this name"
+
+// BadPreset is returned (encapsulated in an error) when RegisterPreset is passed an empty name
+const BadPreset = "a preset must have a name"
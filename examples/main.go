@@ -5,6 +5,7 @@ package main
 // can change depending on IDE and other factors)
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/freebiesoft/reversesearch"
@@ -16,13 +17,22 @@ import (
 // in this default definition
 var logsDir = `.\testdata\test_logs\`
 
-// apache access log patterns
-var apacheStartPattern = `^(?:\S+) (?:\S+) (?:\S+) \[([\w:/]+\s[+\-]\d{4})\]`
-var apacheTimeFormat = `02/Jan/2006:15:04:05 -0700`
+// apache access log patterns; the patterns of common log formats are built in
+// as presets (see reversesearch.PresetNames), and can also be applied directly
+// with reversesearch.ApplyPreset
+var apache, _ = reversesearch.LookupPreset(reversesearch.PresetApache)
+var apacheStartPattern = apache.LeStartPattern
+var apacheTimeFormat = apache.LeTimeFormat
 
 // ODL log patterns
-var odlStartPattern = `^<(\w{3} \d{2}, \d{4} \d{1,2}:\d{2}:\d{2} (?:AM|PM) (?:\S+))>`
-var odlTimeFormat = `Jan 2, 2006 3:04:05 PM MST`
+var odl, _ = reversesearch.LookupPreset(reversesearch.PresetODL)
+var odlStartPattern = odl.LeStartPattern
+var odlTimeFormat = odl.LeTimeFormat
+
+// the ODL log's time stamps are in Indian Standard Time; zone abbreviations are
+// ambiguous (IST is also Irish Standard Time), so they're resolved explicitly
+var ist = time.FixedZone("IST", 5*60*60+30*60)
+var odlTimeZones = map[string]*time.Location{"IST": ist}
 
 // utility method for parsing time objects (useful for inline definitions in structs)
 func parseTime(format string, timeStr string) time.Time {
@@ -33,6 +43,15 @@ func parseTime(format string, timeStr string) time.Time {
 	return t
 }
 
+// utility method for parsing time objects whose zone abbreviation is defined by loc
+func parseTimeIn(format string, timeStr string, loc *time.Location) time.Time {
+	t, err := time.ParseInLocation(format, timeStr, loc)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
 func main() {
 	// file paths
 	accessLog := logsDir + `access.log`
@@ -103,12 +122,13 @@ func main() {
 	searchCriteria = reversesearch.SearchCriteria{
 		LeStartPattern: odlStartPattern,
 		LeTimeFormat:   odlTimeFormat,
+		LeTimeZones:    odlTimeZones,
 		Regexps: []string{
 			// in the test log file, odlLog, 'IAM-1010032' and 'blahblah' are
 			// on separate lines; this is matching over multiple lines
 			`(?s)IAM-1010032.*blahblah`,
 		},
-		FromTime: parseTime(odlTimeFormat, `Jun 17, 2010 11:00:00 PM IST`),
+		FromTime: parseTimeIn(odlTimeFormat, `Jun 17, 2010 11:00:00 PM IST`, ist),
 	}
 	_, err = reversesearch.ReverseSearch(odlLog, &searchCriteria, nil)
 	if err != nil {
@@ -116,12 +136,12 @@ func main() {
 	}
 
 	/*********************************************************************/
-	/************** EXAMPLE 3: CUSTOM OUTPUT HANDLER *********************/
+	/************** EXAMPLE 3: ITERATING OVER MATCHES *******************/
 	/*********************************************************************/
-	// You may define custom output handlers, rather than just having
+	// You may process log entry matches yourself, rather than just having
 	// log entries printed to STDOUT as they're matched. In this example
-	// we create a custom output handler that encapsulates log entry
-	// matches into a JSON string
+	// we iterate over the log entry matches and encapsulate them into a
+	// JSON string
 	fmt.Println("\n\nEXAMPLE 3\n======================================")
 
 	// define search criteria struct
@@ -139,20 +159,17 @@ func main() {
 	var jsonString string
 	jsonString = `{ "matches" : `
 
-	// declare string slice for log entry matches that the output handler can
-	// append to
+	// declare string slice for log entry matches
 	logEntryMatches := []string{}
 
-	// define custom output handler
-	outputHandler = func(logEntry []byte) {
-		logEntryStr := string(logEntry)
-		logEntryMatches = append(logEntryMatches, logEntryStr)
-	}
-
-	// pass log file, search criteria, and our custom output handler to ReverseSearch
-	_, err = reversesearch.ReverseSearch(accessLog, &searchCriteria, outputHandler)
-	if err != nil {
-		panic(err)
+	// rather than defining an output handler, range over the log entry matches
+	// with the Entries iterator (note that logEntry.Bytes is only valid until
+	// the next iteration, hence the conversion to a string)
+	for logEntry, err := range reversesearch.Entries(accessLog, &searchCriteria) {
+		if err != nil {
+			panic(err)
+		}
+		logEntryMatches = append(logEntryMatches, string(logEntry.Bytes))
 	}
 
 	// create the rest of the json string and output to console
@@ -179,8 +196,9 @@ func main() {
 	searchCriteria = reversesearch.SearchCriteria{
 		LeStartPattern: odlStartPattern,
 		LeTimeFormat:   odlTimeFormat,
-		FromTime:       parseTime(odlTimeFormat, `Jun 15, 2010 00:00:00 AM IST`),
-		UntilTime:      parseTime(odlTimeFormat, `Jun 19, 2010 00:00:00 AM IST`),
+		LeTimeZones:    odlTimeZones,
+		FromTime:       parseTimeIn(odlTimeFormat, `Jun 15, 2010 00:00:00 AM IST`, ist),
+		UntilTime:      parseTimeIn(odlTimeFormat, `Jun 19, 2010 00:00:00 AM IST`, ist),
 	}
 
 	// define keywords that we want at least two of in matching log entries
@@ -209,4 +227,39 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+
+	/*********************************************************************/
+	/************** EXAMPLE 5: ENDING THE SEARCH EARLY *******************/
+	/*********************************************************************/
+	// An output handler cannot end the search, so without a FromTime the
+	// whole log file is searched. A control handler can; in this example
+	// the search ends once the 5 most recent matching log entries have
+	// been found.
+	fmt.Println("\n\nEXAMPLE 5\n======================================")
+
+	// define search criteria struct
+	searchCriteria = reversesearch.SearchCriteria{
+		LeStartPattern: apacheStartPattern,
+		Regexps: []string{
+			`/modules/mod_araticlhess1/mod_araticlhess1\.php`,
+		},
+	}
+
+	// define control handler that stops the search after 5 matches
+	matchCount := 0
+	controlHandler := func(logEntry []byte) (reversesearch.Control, error) {
+		fmt.Println(string(logEntry))
+		matchCount++
+		if matchCount == 5 {
+			return reversesearch.Stop, nil
+		}
+		return reversesearch.Continue, nil
+	}
+
+	// pass log file, search criteria, and our control handler to ReverseSearchControl
+	_, err = reversesearch.ReverseSearchControl(context.Background(), accessLog,
+		&searchCriteria, controlHandler)
+	if err != nil {
+		panic(err)
+	}
 }
@@ -0,0 +1,130 @@
+package reversesearch_test
+
+/* Integration tests for LeStartPattern's named capturing groups, i.e. the "ts"
+group that captures the time stamp, the other groups whose values are passed on
+in LogEntry.Fields, and the groups that LeTimeTemplate assembles the time stamp
+from. */
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/freebiesoft/reversesearch"
+)
+
+// TestFields checks that the time stamp can be captured by a group named ts
+// among other groups, and that the other named groups' values are passed on
+func TestFields(t *testing.T) {
+	StartBufLen = 256
+	logFile := filepath.Join(t.TempDir(), "fields.log")
+	check(ioutil.WriteFile(logFile, []byte(strings.Join([]string{
+		"<Jun 15, 2010 2:01:20 AM IST> <Error> [main] <oracle.iam> one",
+		"stack trace",
+		"<Jun 16, 2010 2:02:02 AM IST> <Warning> <oracle.iam> two",
+		"<Jun 17, 2010 2:02:02 AM IST> <Info> [worker-1] <oracle.adf> three",
+	}, "\n")+"\n"), 0644))
+
+	searchCriteria := SearchCriteria{
+		FromTime: parseTime(odlTimeFormat, `Jun 16, 2010 12:00:00 AM IST`),
+		LeStartPattern: `^<(?P<ts>\w{3} \d{2}, \d{4} \d{1,2}:\d{2}:\d{2} (?:AM|PM) \S+)> ` +
+			`<(?P<level>\w+)> (?:\[(?P<thread>[^\]]+)\] )?<(?P<logger>[^>]+)>`,
+		LeTimeFormat: odlTimeFormat,
+	}
+	searcher, err := NewSearcher(&searchCriteria, nil)
+	check(err)
+
+	var tests = []struct {
+		name           string
+		forward        bool
+		expectedFields []map[string]string
+		expectedTimes  []string
+	}{
+		{
+			name: "test 1: reverse search",
+			expectedFields: []map[string]string{
+				{"level": "Info", "thread": "worker-1", "logger": "oracle.adf"},
+				{"level": "Warning", "logger": "oracle.iam"},
+			},
+			expectedTimes: []string{`Jun 17, 2010 2:02:02 AM IST`, `Jun 16, 2010 2:02:02 AM IST`},
+		},
+		{
+			name:    "test 2: forward search",
+			forward: true,
+			expectedFields: []map[string]string{
+				{"level": "Warning", "logger": "oracle.iam"},
+				{"level": "Info", "thread": "worker-1", "logger": "oracle.adf"},
+			},
+			expectedTimes: []string{`Jun 16, 2010 2:02:02 AM IST`, `Jun 17, 2010 2:02:02 AM IST`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var fields []map[string]string
+			var times []string
+			handler := func(logEntry LogEntry) (Control, error) {
+				fields = append(fields, logEntry.Fields)
+				times = append(times, logEntry.Time.Format(odlTimeFormat))
+				return Continue, nil
+			}
+			if test.forward {
+				_, err = searcher.ForwardSearch(logFile, handler)
+			} else {
+				_, err = searcher.ReverseSearch(context.Background(), logFile, handler)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(fields, test.expectedFields) {
+				t.Errorf("Got fields: %v, want fields: %v", fields, test.expectedFields)
+			}
+			if !reflect.DeepEqual(times, test.expectedTimes) {
+				t.Errorf("Got times: %q, want times: %q", times, test.expectedTimes)
+			}
+		})
+	}
+}
+
+// TestLeTimeTemplate checks that a time stamp split across several capturing
+// groups is assembled with LeTimeTemplate before it's parsed
+func TestLeTimeTemplate(t *testing.T) {
+	StartBufLen = 256
+	logFile := filepath.Join(t.TempDir(), "vendor.log")
+	lines := []string{
+		"2024-05-01 | 12:03:44.123 | INFO | started",
+		"2024-05-01 | 12:05:10.002 | WARN | slow",
+		"2024-05-02 | 09:00:00.500 | INFO | stopped",
+	}
+	check(ioutil.WriteFile(logFile, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+	leTimeFormat := `2006-01-02 15:04:05.000`
+
+	var actual []string
+	_, err := ReverseSearchWithOptions(context.Background(), logFile, &SearchCriteria{
+		FromTime:       parseTime(leTimeFormat, `2024-05-01 12:04:00.000`),
+		UntilTime:      parseTime(leTimeFormat, `2024-05-02 09:00:00.500`),
+		LeStartPattern: `^(?P<date>\d{4}-\d{2}-\d{2}) \| (?P<time>[\d:.]+) \|`,
+		LeTimeFormat:   leTimeFormat,
+		LeTimeTemplate: `${date} ${time}`,
+	}, nil, collectEntries(&actual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, lines[1:2]) {
+		t.Errorf("GOT:\n%q\nWANT:\n%q", actual, lines[1:2])
+	}
+
+	// the layout of the assembled time stamp is needed
+	_, err = NewSearcher(&SearchCriteria{
+		LeStartPattern: `^(?P<date>\S+) \| (?P<time>\S+)`,
+		LeTimeTemplate: `${date} ${time}`,
+	}, nil)
+	if !errors.Is(err, ErrNoLeTimeFormat) {
+		t.Errorf("Got error: %v, want error that is: %v", err, ErrNoLeTimeFormat)
+	}
+}
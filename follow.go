@@ -0,0 +1,423 @@
+package reversesearch
+
+/* The follow mode functions are contained in this file:
+- findLastLeOffset
+- follower (type) and its methods
+- Follow (exported)
+
+Follow gives "tail -f" semantics; it reverse searches the log file for recent
+history, and then polls the log file for newly appended log entries.
+*/
+
+import (
+	"context"
+	"io"
+	"os"
+	"regexp"
+	"time"
+)
+
+// DefaultPollInterval is the interval at which Follow checks the log file for
+// new bytes when FollowOptions.PollInterval is not set
+var DefaultPollInterval = 500 * time.Millisecond
+
+// DefaultIdleTimeout is the amount of time Follow waits without any new bytes
+// being appended before it considers the last log entry in the file complete,
+// when FollowOptions.IdleTimeout is not set
+var DefaultIdleTimeout = 2 * time.Second
+
+// FollowOptions holds optional settings for Follow. A nil *FollowOptions, or
+// zero valued fields, mean the defaults are used.
+type FollowOptions struct {
+	// PollInterval is the interval at which the log file is checked for new
+	// bytes, truncation and rotation
+	PollInterval time.Duration
+
+	// IdleTimeout is how long to wait without any new bytes being appended before
+	// the last log entry in the file is considered complete and is processed.
+	// Until then, a log entry is only processed once the next line that matches
+	// LeStartPattern has been appended.
+	IdleTimeout time.Duration
+
+	// Search holds the settings of the search (see Options). A nil Search means
+	// the defaults are used.
+	Search *Options
+}
+
+// findLastLeOffset returns the position of the first byte of the last log
+// entry within the first fileSize bytes of file (i.e. the last line that
+// matches leStartRegexp). The bytes are read backwards from fileSize in windows
+// that start at opts.StartBufLen in length and double up to opts.MaxBufLen. The
+// second return value is false if there are no log entries in the file.
+func findLastLeOffset(file io.ReaderAt, fileSize int64,
+	leStartRegexp *regexp.Regexp, opts *Options) (int64, bool, error) {
+
+	windowLen := int64(opts.StartBufLen)
+	for fileSize > 0 {
+		if windowLen > fileSize {
+			windowLen = fileSize
+		}
+		offset := fileSize - windowLen
+		buf := make([]byte, windowLen)
+		if _, err := file.ReadAt(buf, offset); err != nil {
+			return 0, false, err
+		}
+
+		// find the last line that matches leStartRegexp, ignoring the first line
+		// in buf as it may have been cut off
+		lastLePos := -1
+		skipping := offset > 0
+		lineStart := 0
+		for {
+			lineEnd, nextLineStart, found := nextLine(buf, lineStart, len(buf), opts.Newlines)
+			if skipping {
+				skipping = false
+			} else if leStartRegexp.Match(buf[lineStart:lineEnd]) {
+				lastLePos = lineStart
+			}
+			if !found {
+				break
+			}
+			lineStart = nextLineStart
+		}
+
+		if lastLePos >= 0 {
+			return offset + int64(lastLePos), true, nil
+		}
+		if offset == 0 {
+			break
+		}
+		if windowLen >= int64(opts.MaxBufLen) {
+			return 0, false, ErrMaxBufLenReached
+		}
+		windowLen *= 2
+		if windowLen > int64(opts.MaxBufLen) {
+			windowLen = int64(opts.MaxBufLen)
+		}
+	}
+	return 0, false, nil
+}
+
+// follower processes bytes as they're appended to a log file. Lines are only
+// analysed once they're complete (i.e. once their newline has been appended),
+// and a log entry is only passed on once the first line of the next log entry
+// has been found, or when flush is called.
+type follower struct {
+	leStartRegexp  *regexp.Regexp
+	regexps        []*regexp.Regexp
+	searchCriteria *SearchCriteria
+	handler        EntryHandler
+	opts           *Options
+	badTimes       *badTimeState
+	timeParser     *timeParser
+
+	// buf holds the bytes that have been appended but not yet processed, starting
+	// from the pending log entry if there is one, and bufOffset is the position
+	// in the log file of buf[0]
+	buf       []byte
+	bufOffset int64
+
+	// lineStart is the buf position of the first line yet to be analysed, and
+	// prevNlPos is the buf position of the newline preceding it
+	lineStart int
+	prevNlPos int
+
+	// lePos is the buf position at which the pending log entry starts (-1 if
+	// there isn't one), leSatisfied indicates if it satisfied FromTime, and
+	// leTime is its time of logging
+	lePos       int
+	leSatisfied bool
+	leTime      time.Time
+
+	// done is set once a log entry has failed UntilTime, or handler has returned
+	// Stop, after which no further log entries are passed on
+	done bool
+}
+
+// newFollower returns a follower with no pending log entry, that will be
+// written bytes from offset onwards. opts must already have been resolved (see
+// resolveOptions).
+func newFollower(leStartRegexp *regexp.Regexp, regexps []*regexp.Regexp,
+	searchCriteria *SearchCriteria, handler EntryHandler, opts *Options,
+	offset int64) *follower {
+	return &follower{
+		leStartRegexp:  leStartRegexp,
+		regexps:        regexps,
+		searchCriteria: searchCriteria,
+		handler:        handler,
+		opts:           opts,
+		badTimes:       newBadTimeState(opts),
+		timeParser:     newTimeParser(leStartRegexp, searchCriteria, opts.YearReference, false),
+		bufOffset:      offset,
+		lePos:          -1,
+	}
+}
+
+// write appends newly read bytes and processes any lines they complete
+func (f *follower) write(b []byte) error {
+	if len(f.buf)+len(b) > f.opts.MaxBufLen {
+		return ErrMaxBufLenReached
+	}
+	f.buf = append(f.buf, b...)
+	return f.processLines(false)
+}
+
+// processPendingLogEntry passes the pending log entry, which ends at prevNlPos,
+// on to processLogEntry (unless the follower is done), marking the follower as
+// done if handler returns Stop
+func (f *follower) processPendingLogEntry() error {
+	if f.done {
+		return nil
+	}
+	control, err := processLogEntry(f.buf[f.lePos:f.prevNlPos], f.leTime,
+		f.bufOffset+int64(f.lePos), f.opts.MaxEntryLen, f.leStartRegexp, f.regexps, f.handler)
+	if control == Stop {
+		f.done = true
+	}
+	return err
+}
+
+// flush processes the last line even if it is incomplete, then processes the
+// pending log entry, treating it as complete. It is used when the log file has
+// been idle for long enough, or has been truncated or rotated.
+func (f *follower) flush() error {
+	if err := f.processLines(true); err != nil {
+		return err
+	}
+	if f.lePos >= 0 && f.leSatisfied {
+		if err := f.processPendingLogEntry(); err != nil {
+			return err
+		}
+	}
+	f.bufOffset += int64(len(f.buf))
+	f.buf = f.buf[:0]
+	f.lineStart, f.prevNlPos, f.lePos = 0, 0, -1
+	return nil
+}
+
+// processLines analyses each complete line in buf (and the incomplete last line
+// too if final is set). Bytes that are no longer needed are then discarded.
+func (f *follower) processLines(final bool) error {
+	for !f.done && f.lineStart < len(f.buf) {
+		lineEnd, nextLineStart, found := nextLine(f.buf, f.lineStart, len(f.buf),
+			f.opts.Newlines)
+		if !found && !final {
+			break
+		}
+
+		startOfLe, fromTimeSatisfied, untilTimeSatisfied, leTime, err := processLine(
+			f.buf[f.lineStart:lineEnd], f.bufOffset+int64(f.lineStart), f.leStartRegexp,
+			f.timeParser, f.searchCriteria.FromTime, f.searchCriteria.UntilTime, f.badTimes,
+		)
+		if err == errSkipLogEntry {
+			// the log entry isn't passed on, but following continues
+			fromTimeSatisfied, untilTimeSatisfied, err = false, true, nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if startOfLe {
+			// the pending log entry ends at the newline preceding this line
+			if f.lePos >= 0 && f.leSatisfied {
+				if err := f.processPendingLogEntry(); err != nil {
+					return err
+				}
+				if f.done {
+					break
+				}
+			}
+			f.lePos = f.lineStart
+			f.leSatisfied = fromTimeSatisfied && untilTimeSatisfied
+			f.leTime = leTime
+			if !untilTimeSatisfied && !leTime.Before(
+				f.searchCriteria.UntilTime.Add(f.searchCriteria.Tolerance)) {
+				f.lePos = -1
+				f.done = true
+			}
+		}
+
+		f.prevNlPos = lineEnd
+		f.lineStart = nextLineStart
+	}
+
+	// discard bytes before the pending log entry (or before the first line yet
+	// to be analysed if there isn't one)
+	keepPos := f.lineStart
+	if f.lePos >= 0 {
+		keepPos = f.lePos
+	}
+	if keepPos > 0 {
+		f.buf = f.buf[:copy(f.buf, f.buf[keepPos:])]
+		f.bufOffset += int64(keepPos)
+		f.lineStart -= keepPos
+		f.prevNlPos -= keepPos
+		if f.lePos >= 0 {
+			f.lePos -= keepPos
+		}
+	}
+	return nil
+}
+
+// Follow gives "tail -f" semantics. It begins by reverse searching the log file
+// specified by filePath for matching log entries (i.e. recent history, most
+// recent first, as ReverseSearch would), and then keeps polling the log file,
+// passing log entries that match the search criteria to outputHandler as they're
+// appended (oldest first). The last log entry in the file is held back until
+// the first line of the next log entry is appended, or until no bytes have
+// been appended for the idle timeout, since until then it may be incomplete.
+//
+// If the log file is truncated, or replaced by a new file with the same name
+// (i.e. rotated, which is detected by a change of inode), the pending log entry
+// is processed and following continues from the beginning of the file. Follow
+// returns once ctx is done, or once a log entry fails searchCriteria.UntilTime,
+// since no further log entries could match. Return values are the same as
+// those of ReverseSearch, with an exit status of 0 in both these cases.
+func Follow(ctx context.Context, filePath string, searchCriteria *SearchCriteria,
+	outputHandler OutputHandler, options *FollowOptions) (int, error) {
+
+	// validate parameters
+	if err := validateSearchCriteria(searchCriteria); err != nil {
+		return -1, err
+	}
+	pollInterval, idleTimeout := DefaultPollInterval, DefaultIdleTimeout
+	if options != nil && options.PollInterval > 0 {
+		pollInterval = options.PollInterval
+	}
+	if options != nil && options.IdleTimeout > 0 {
+		idleTimeout = options.IdleTimeout
+	}
+	var opts *Options
+	if options != nil {
+		opts = options.Search
+	}
+	opts = resolveOptions(opts)
+
+	// open file
+	file, err := os.Open(filePath)
+	if err != nil {
+		return -1, err
+	}
+	defer func() { file.Close() }()
+
+	// get file size
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return -1, err
+	}
+
+	// compile searchCriteria.LeStartPattern and searchCriteria.Regexps
+	leStartRegexp, regexps, err := compileSearchCriteria(searchCriteria)
+	if err != nil {
+		return -1, err
+	}
+
+	// if user did not specify an output handler, set it to fmt.Println
+	handler := outputEntryHandler(outputHandler)
+
+	// reverse search everything before the last log entry, which is left for the
+	// follower as it may not have been completely written yet
+	offset, found, err := findLastLeOffset(file, fileInfo.Size(), leStartRegexp, opts)
+	if err != nil {
+		return -1, err
+	}
+	if found && offset > 0 {
+		exitStatus, _, err := reverseSearch(ctx, file, offset, leStartRegexp, regexps,
+			searchCriteria, handler, opts)
+		if err != nil {
+			if ctx.Err() != nil { // ctx was done during the reverse search
+				return 0, nil
+			}
+			return exitStatus, err
+		}
+	}
+
+	f := newFollower(leStartRegexp, regexps, searchCriteria, handler, opts, offset)
+	defer func() { f.badTimes.report(opts.Stats) }()
+	buf := make([]byte, opts.StartBufLen)
+	lastAppend := time.Now()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	// readAppended reads any bytes that have been appended to file since the
+	// last read, reporting whether there were any. Reaching the end of file is
+	// not an error, but any other read error is returned.
+	readAppended := func() (bool, error) {
+		appended := false
+		for {
+			n, err := file.ReadAt(buf, offset)
+			if n > 0 {
+				appended = true
+				offset += int64(n)
+				if err := f.write(buf[:n]); err != nil {
+					return appended, err
+				}
+			}
+			if err != nil && err != io.EOF {
+				return appended, err
+			}
+			if err != nil || n < len(buf) {
+				return appended, nil
+			}
+		}
+	}
+
+	for {
+		appended, err := readAppended()
+		if err != nil {
+			return -1, err
+		}
+		if f.done {
+			return 0, nil
+		}
+
+		// check for truncation and rotation
+		newFileInfo, err := os.Stat(filePath)
+		if err == nil && !os.SameFile(fileInfo, newFileInfo) {
+			// the log file has been rotated; once everything in the old file has
+			// been read, its last log entry is complete
+			if _, err := readAppended(); err != nil {
+				return -1, err
+			}
+			if err := f.flush(); err != nil {
+				return -1, err
+			}
+			newFile, err := os.Open(filePath)
+			if err != nil {
+				return -1, err
+			}
+			file.Close()
+			file, offset, f.bufOffset = newFile, 0, 0
+			if fileInfo, err = file.Stat(); err != nil {
+				return -1, err
+			}
+			lastAppend = time.Now()
+			continue
+		} else if err == nil && newFileInfo.Size() < offset {
+			// the log file has been truncated
+			if err := f.flush(); err != nil {
+				return -1, err
+			}
+			offset, f.bufOffset = 0, 0
+			lastAppend = time.Now()
+			continue
+		}
+
+		if appended {
+			lastAppend = time.Now()
+		} else if time.Since(lastAppend) >= idleTimeout {
+			if err := f.flush(); err != nil {
+				return -1, err
+			}
+		}
+		if f.done {
+			return 0, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package reversesearch_test
+
+/* Integration tests for Follow. These tests append to, truncate and rotate a
+temporary log file while Follow is polling it, so they wait (for up to a few
+seconds) for the expected log entries to be passed to the output handler. */
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/freebiesoft/reversesearch"
+)
+
+// followLines are ODL log entries used throughout the Follow tests
+var followLines = []string{
+	"<Jun 15, 2010 2:01:20 AM IST> <Error> one",
+	"<Jun 15, 2010 2:02:02 AM IST> <Warning> two",
+	"<Jun 16, 2010 2:02:02 AM IST> <Warning> three",
+	"<Jun 17, 2010 2:02:02 AM IST> <Warning> four",
+	"<Jun 18, 2010 2:02:02 AM IST> <Warning> five",
+}
+
+// followCollector collects the log entries passed to its handler by Follow
+type followCollector struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (c *followCollector) handler(logEntry []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, string(logEntry))
+}
+
+// waitFor waits until the collected log entries equal expected, failing the
+// test if that doesn't happen within a few seconds
+func (c *followCollector) waitFor(t *testing.T, expected []string) {
+	t.Helper()
+	var actual []string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		c.mu.Lock()
+		actual = append([]string(nil), c.entries...)
+		c.mu.Unlock()
+		if reflect.DeepEqual(actual, expected) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("GOT:\n%s\nWANT:\n%s", strings.Join(actual, "\n"),
+		strings.Join(expected, "\n"))
+}
+
+// appendToFile appends s to the file at filePath
+func appendToFile(filePath string, s string) {
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	check(err)
+	defer file.Close()
+	_, err = file.WriteString(s)
+	check(err)
+}
+
+// startFollow runs Follow in a goroutine and returns a function that stops it
+// and checks it returned without error
+func startFollow(t *testing.T, filePath string, searchCriteria *SearchCriteria,
+	c *followCollector, idleTimeout time.Duration) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := Follow(ctx, filePath, searchCriteria, c.handler, &FollowOptions{
+			PollInterval: 5 * time.Millisecond,
+			IdleTimeout:  idleTimeout,
+		})
+		done <- err
+	}()
+	return func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestFollowAppended checks that history is reverse searched, and that
+// appended log entries are only passed on once they're complete
+func TestFollowAppended(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "follow.log")
+	appendToFile(logFile, followLines[0]+"\n"+followLines[1]+"\n")
+
+	c := &followCollector{}
+	stop := startFollow(t, logFile, &SearchCriteria{LeStartPattern: odlStartPattern},
+		c, time.Hour)
+	defer stop()
+
+	// history is reverse searched, but the last log entry could still be
+	// being written to, so it is held back
+	c.waitFor(t, followLines[:1])
+
+	// a partially written line followed by a continuation line doesn't complete
+	// the pending log entry
+	appendToFile(logFile, "continued\n"+followLines[2][:10])
+	time.Sleep(50 * time.Millisecond)
+	c.waitFor(t, followLines[:1])
+
+	// completing the first line of the next log entry does
+	appendToFile(logFile, followLines[2][10:]+"\n")
+	c.waitFor(t, []string{followLines[0], followLines[1] + "\ncontinued"})
+}
+
+// TestFollowIdleTimeout checks that the last log entry is passed on once
+// the log file has been idle for the idle timeout
+func TestFollowIdleTimeout(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "follow.log")
+	appendToFile(logFile, followLines[0]+"\n")
+
+	c := &followCollector{}
+	stop := startFollow(t, logFile, &SearchCriteria{LeStartPattern: odlStartPattern},
+		c, 50*time.Millisecond)
+	defer stop()
+
+	c.waitFor(t, followLines[:1])
+	appendToFile(logFile, followLines[1])
+	c.waitFor(t, followLines[:2])
+}
+
+// TestFollowTruncateAndRotate checks that following continues from the
+// beginning of the log file after it has been truncated or rotated
+func TestFollowTruncateAndRotate(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "follow.log")
+	appendToFile(logFile, followLines[0]+"\n")
+
+	c := &followCollector{}
+	stop := startFollow(t, logFile, &SearchCriteria{LeStartPattern: odlStartPattern},
+		c, time.Hour)
+	defer stop()
+
+	// truncation processes the pending log entry
+	appendToFile(logFile, followLines[1]+"\n")
+	c.waitFor(t, followLines[:1])
+	check(os.Truncate(logFile, 0))
+	c.waitFor(t, followLines[:2])
+
+	// rotation processes the pending log entry from the old file
+	appendToFile(logFile, followLines[2]+"\n")
+	time.Sleep(50 * time.Millisecond)
+	check(os.Rename(logFile, logFile+".1"))
+	appendToFile(logFile, followLines[3]+"\n"+followLines[4]+"\n")
+	c.waitFor(t, followLines[:4])
+}
+
+// TestFollowUntilTime checks that Follow returns once a log entry fails
+// UntilTime
+func TestFollowUntilTime(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "follow.log")
+	appendToFile(logFile, followLines[0]+"\n"+followLines[1]+"\n")
+
+	searchCriteria := SearchCriteria{
+		UntilTime:      parseTime(odlTimeFormat, `Jun 16, 2010 12:00:00 AM IST`),
+		LeStartPattern: odlStartPattern,
+		LeTimeFormat:   odlTimeFormat,
+	}
+	c := &followCollector{}
+	done := make(chan error, 1)
+	go func() {
+		_, err := Follow(context.Background(), logFile, &searchCriteria, c.handler,
+			&FollowOptions{PollInterval: 5 * time.Millisecond, IdleTimeout: time.Hour})
+		done <- err
+	}()
+
+	c.waitFor(t, followLines[:1])
+	appendToFile(logFile, followLines[2]+"\n")
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Follow did not return after UntilTime failed")
+	}
+	c.waitFor(t, followLines[:2])
+}
@@ -0,0 +1,272 @@
+package reversesearch
+
+/* The forward search functions are contained in this file:
+- nextLine
+- growBuf
+- forwardSearch
+- ForwardSearch (exported)
+
+ForwardSearch shares processLine, processLogEntry and the search criteria
+helpers with ReverseSearch so that both functions interpret log entries,
+newlines and time constraints in exactly the same way.
+*/
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"time"
+)
+
+// nextLine looks for the end of the line that starts at buf[lineStart], only
+// considering the bytes in buf[:n]. The return values are:
+// 1) lineEnd (int): the position in buf at which the line's newline starts (i.e.
+// the position of \r for a \r\n newline), or n if no newline was found
+// 2) nextLineStart (int): the position in buf just after the line's newline
+// 3) found (bool): indicates if a newline was found within buf[lineStart:n]
+// A \r preceding the \n is only considered part of the newline if newlines
+// allows \r\n newlines.
+func nextLine(buf []byte, lineStart int, n int, newlines NewlinePolicy) (int, int, bool) {
+	nlIndex := bytes.IndexByte(buf[lineStart:n], '\n')
+	if nlIndex < 0 {
+		return n, n, false
+	}
+
+	lineEnd := lineStart + nlIndex
+	nextLineStart := lineEnd + 1
+	if newlines.crlf() && lineEnd > lineStart && buf[lineEnd-1] == '\r' {
+		lineEnd--
+	}
+	return lineEnd, nextLineStart, true
+}
+
+// growBuf doubles the length of buf (without exceeding maxBufLen), keeping the
+// existing elements at the beginning of the buf. An error is returned if the
+// maximum buffer length has already been reached.
+func growBuf(buf []byte, maxBufLen int) ([]byte, error) {
+	if len(buf) >= maxBufLen {
+		return buf, ErrMaxBufLenReached
+	}
+	newBufLen := len(buf) * 2
+	if newBufLen == 0 { // sanity check
+		newBufLen = 1
+	} else if newBufLen > maxBufLen {
+		newBufLen = maxBufLen
+	}
+	newBuf := make([]byte, newBufLen)
+	copy(newBuf, buf)
+	return newBuf, nil
+}
+
+// forwardSearch traverses file forwards, starting at startOffset, passing log
+// entries that match the search criteria to handler as they're found (ending
+// the search early if handler returns Stop). fileSize should already have had
+// its trailing newline discounted, and startOffset must either be 0 or the
+// position of the first byte of a log entry. opts must already have been
+// resolved (see resolveOptions). It returns the same values as ForwardSearch.
+func forwardSearch(file io.ReaderAt, fileSize int64, startOffset int64,
+	leStartRegexp *regexp.Regexp, regexps []*regexp.Regexp,
+	searchCriteria *SearchCriteria, handler EntryHandler, opts *Options) (int, error) {
+
+	// initialise buf related variables
+	var bufLen int
+	if int64(opts.StartBufLen) > fileSize-startOffset {
+		bufLen = int(fileSize - startOffset)
+	} else {
+		bufLen = opts.StartBufLen
+	}
+	pooledBuf := opts.getBuf(bufLen)
+	defer opts.putBuf(pooledBuf)
+	buf := pooledBuf
+
+	// bufOffset is the position in the file of buf[0], and n is the number of
+	// bytes in buf that have been read from the file
+	bufOffset := startOffset
+	var n int
+	var err error
+
+	// lineStart is the buf position of the start of the line being analysed, and
+	// prevNlPos is the buf position of the newline preceding it
+	var lineStart int
+	var prevNlPos int
+
+	// lePos is the buf position at which the pending log entry starts (-1 if no
+	// log entry has been found yet), leSatisfied indicates if the pending log
+	// entry satisfied searchCriteria.FromTime, and leTime is its time of logging
+	lePos := -1
+	leSatisfied := false
+	var leTime time.Time
+
+	// firstLeOffset records the file position of the newline preceding the first
+	// log entry, and preamble is set when bytes other than a single newline are
+	// found before the first log entry. keepPreamble indicates if the bytes
+	// before the first log entry must be kept in buf so that they can be passed
+	// to handler (see PreambleEntry).
+	firstLeOffset := int64(-1)
+	preamble := false
+	keepPreamble := opts.Preamble == PreambleEntry && startOffset == 0
+
+	// keep track of log entries whose time stamps can't be parsed
+	badTimes := newBadTimeState(opts)
+	defer badTimes.report(opts.Stats)
+	timeParser := newTimeParser(leStartRegexp, searchCriteria, yearReference(file, opts), false)
+
+	for {
+		lineEnd, nextLineStart, found := nextLine(buf, lineStart, n, opts.Newlines)
+		eof := bufOffset+int64(n) >= fileSize
+
+		if !found && !eof {
+			// the line continues beyond the bytes read so far; keep the bytes of the
+			// pending log entry (or of the current line if there isn't one) and read
+			// more bytes into buf after them
+			keepPos := lineStart
+			if lePos >= 0 {
+				keepPos = lePos
+			} else if keepPreamble {
+				keepPos = 0
+			}
+
+			if keepPos == 0 && n == len(buf) {
+				// buf is full of bytes that are still needed, so increase its length
+				buf, err = growBuf(buf, opts.MaxBufLen)
+				if err != nil {
+					return -1, err
+				}
+			} else if keepPos > 0 {
+				// shift the bytes that are still needed to the beginning of buf
+				copy(buf, buf[keepPos:n])
+				n -= keepPos
+				bufOffset += int64(keepPos)
+				lineStart -= keepPos
+				prevNlPos -= keepPos
+				if lePos >= 0 {
+					lePos -= keepPos
+				}
+			}
+
+			// read as many bytes from the file as will fit in buf
+			readLen := len(buf) - n
+			if remaining := fileSize - bufOffset - int64(n); int64(readLen) > remaining {
+				readLen = int(remaining)
+			}
+			err = readAt(file, buf[n:n+readLen], bufOffset+int64(n))
+			if err != nil {
+				return -1, err
+			}
+			n += readLen
+			continue
+		}
+
+		// determine if the line is the first line of a log entry and if so, if it
+		// satisfies time constraints
+		startOfLe, fromTimeSatisfied, untilTimeSatisfied, lineTime, err := processLine(
+			buf[lineStart:lineEnd], bufOffset+int64(lineStart), leStartRegexp, timeParser,
+			searchCriteria.FromTime, searchCriteria.UntilTime, badTimes,
+		)
+		if err == errSkipLogEntry {
+			// the log entry isn't passed on, but the search continues
+			fromTimeSatisfied, untilTimeSatisfied, err = false, true, nil
+		}
+		if err != nil {
+			return -1, err
+		}
+
+		if startOfLe {
+			// the preamble precedes the first log entry, so it's passed on first
+			// (unless the first log entry fails the time constraints, in which
+			// case the preamble is considered to fail them too)
+			if lePos < 0 && preamble && keepPreamble && fromTimeSatisfied &&
+				untilTimeSatisfied {
+				control, err := processPreamble(buf[:prevNlPos], opts.MaxEntryLen, regexps,
+					handler)
+				if err != nil {
+					return -1, err
+				}
+				if control == Stop {
+					return 0, nil
+				}
+			}
+
+			// the pending log entry ends at the newline preceding this line
+			if lePos >= 0 && leSatisfied {
+				control, err := processLogEntry(buf[lePos:prevNlPos], leTime,
+					bufOffset+int64(lePos), opts.MaxEntryLen, leStartRegexp, regexps, handler)
+				if err != nil {
+					return -1, err
+				}
+				if control == Stop {
+					return 0, nil
+				}
+			}
+			if firstLeOffset < 0 {
+				firstLeOffset = bufOffset + int64(prevNlPos)
+			}
+			if !untilTimeSatisfied && !lineTime.Before(
+				searchCriteria.UntilTime.Add(searchCriteria.Tolerance)) {
+				// no further log entries in the log file can match, so stop here
+				lePos = -1
+				break
+			}
+			lePos = lineStart
+			leSatisfied = fromTimeSatisfied && untilTimeSatisfied
+			leTime = lineTime
+		} else if lePos < 0 {
+			// the only bytes allowed before the first log entry is a single newline
+			// at the beginning of the file
+			if bufOffset+int64(lineStart) != 0 || lineEnd != lineStart {
+				preamble = true
+			}
+		}
+
+		prevNlPos = lineEnd
+		if !found { // last line in the file
+			break
+		}
+		lineStart = nextLineStart
+	}
+
+	// the last pending log entry ends at the end of the file
+	if lePos >= 0 && leSatisfied {
+		control, err := processLogEntry(buf[lePos:n], leTime, bufOffset+int64(lePos),
+			opts.MaxEntryLen, leStartRegexp, regexps, handler)
+		if err != nil {
+			return -1, err
+		}
+		if control == Stop {
+			return 0, nil
+		}
+	}
+
+	// check to see if we found no log entries, or if there were bytes before
+	// the first log entry (and they're not to be ignored)
+	if firstLeOffset < 0 {
+		return -1, ErrNoLogEntriesInFile
+	}
+	if preamble && opts.Preamble == PreambleError {
+		return -1, &NoMoreEntriesError{Offset: firstLeOffset}
+	}
+
+	return 0, nil
+}
+
+// ForwardSearch searches the log file specified by filePath for matching log
+// entries, starting at the beginning of the file. It is the counterpart of
+// ReverseSearch; matching log entries are passed to outputHandler in the order
+// they appear in the file (i.e. oldest first), and the first log entry that
+// fails the searchCriteria.UntilTime constraint will trigger the abort mechanism,
+// which will end the search process. Log entries that fail the
+// searchCriteria.FromTime constraint are skipped. Return values are the same as
+// those of ReverseSearch:
+//
+// 1) exitStatus (int): -1 indicates an error was found, 0 indicates normal
+// execution without issues, 1 indicates file is empty (not considered an error)
+//
+// 2) err (error)
+func ForwardSearch(filePath string, searchCriteria *SearchCriteria,
+	outputHandler OutputHandler) (int, error) {
+	searcher, err := NewSearcher(searchCriteria, nil)
+	if err != nil {
+		return -1, err
+	}
+	return searcher.ForwardSearch(filePath, outputEntryHandler(outputHandler))
+}
@@ -0,0 +1,271 @@
+package reversesearch_test
+
+/* Integration tests for ForwardSearch. Rather than keeping a second set of
+expected output files, the green path tests check that ForwardSearch matches the
+same log entries as ReverseSearch, but in the opposite order. */
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/freebiesoft/reversesearch"
+)
+
+// Green path testing of ForwardSearch function in reversesearch package
+func TestGreenPathsForwardSearch(t *testing.T) {
+	// set fairly small StartBufLen for test context, so bugs are more likely
+	// to be caught
+	StartBufLen = 256
+	origStartBufLen := StartBufLen
+
+	var tests = []struct {
+		name           string         // test name (also description summary)
+		filePath       string         // path of file to be searched
+		searchCriteria SearchCriteria // second parameter of ForwardSearch
+		setUp          func()         // set up actions
+		cleanUp        func()         // clean up actions
+	}{
+		{
+			name:     "test 1: regexp match",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				Regexps:        []string{`/modules/mod_araticlhess1/mod_araticlhess1\.php`},
+				LeStartPattern: apacheStartPattern,
+			},
+		},
+		{
+			name:     "test 2: time range constraint",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(apacheTimeFormat, `23/Sep/2019:00:00:00 +0200`),
+				UntilTime:      parseTime(apacheTimeFormat, `23/Sep/2019:00:30:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+		},
+		{
+			name:     "test 3: multiline entries with untilTime constraint",
+			filePath: odlLog,
+			searchCriteria: SearchCriteria{
+				UntilTime:      parseTime(odlTimeFormat, `Jun 17, 2010 11:00:00 PM IST`),
+				LeStartPattern: odlStartPattern,
+				LeTimeFormat:   odlTimeFormat,
+			},
+		},
+		{
+			name:     `test 4: \n prefix`,
+			filePath: accessLogNlPrefixUnix,
+			searchCriteria: SearchCriteria{
+				LeStartPattern: apacheStartPattern,
+			},
+		},
+		{
+			name:     `test 5: \r\n prefix`,
+			filePath: accessLogNlPrefixWin,
+			searchCriteria: SearchCriteria{
+				LeStartPattern: apacheStartPattern,
+			},
+		},
+		{
+			name:     `test 6: no \n suffix`,
+			filePath: odlLogNoNlSuffixUnix,
+			searchCriteria: SearchCriteria{
+				LeStartPattern: odlStartPattern,
+			},
+		},
+		{
+			name:     `test 7: no \r\n suffix`,
+			filePath: odlLogNoNlSuffixWin,
+			searchCriteria: SearchCriteria{
+				LeStartPattern: odlStartPattern,
+			},
+		},
+		{
+			name:     "test 8: single multiline log entry",
+			filePath: singleLog,
+			searchCriteria: SearchCriteria{
+				LeStartPattern: odlStartPattern,
+			},
+		},
+		{
+			name:     "test 9: buf len increasing",
+			filePath: oneLargeOneSmall,
+			searchCriteria: SearchCriteria{
+				LeStartPattern: odlStartPattern,
+			},
+			setUp:   func() { StartBufLen = 1 },
+			cleanUp: func() { StartBufLen = origStartBufLen },
+		},
+		{
+			name:     "test 10: untilTime before the first log entry",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				UntilTime:      parseTime(apacheTimeFormat, `21/Sep/2019:00:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.setUp != nil {
+				test.setUp()
+			}
+			if test.cleanUp != nil {
+				defer test.cleanUp()
+			}
+
+			// collect log entries found by ReverseSearch in reverse order
+			var expected []string
+			_, err := ReverseSearch(test.filePath, &test.searchCriteria,
+				func(logEntry []byte) {
+					expected = append([]string{string(logEntry)}, expected...)
+				})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			var actual []string
+			exitStatus, err := ForwardSearch(test.filePath, &test.searchCriteria,
+				func(logEntry []byte) { actual = append(actual, string(logEntry)) })
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("ForwardSearch matches are not the reverse of ReverseSearch "+
+					"matches.\nGOT:\n%s\nWANT:\n%s", strings.Join(actual, "\n"),
+					strings.Join(expected, "\n"))
+			}
+			if exitStatus != 0 {
+				t.Errorf("Got exit status: %d, want exit status: 0", exitStatus)
+			}
+		})
+	}
+}
+
+// TestForwardSearchStopsAfterUntilTime checks that ForwardSearch does not read
+// any further than the first log entry that fails UntilTime; the log file used
+// has a log entry with a malformed timestamp beyond that point which would cause
+// an error if it were processed
+func TestForwardSearchStopsAfterUntilTime(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "until.log")
+	check(ioutil.WriteFile(logFile, []byte(
+		"<Jun 15, 2010 2:01:20 AM IST> <Error> first\n"+
+			"continued\n"+
+			"<Jun 15, 2010 2:02:02 AM IST> <Warning> second\n"+
+			"<Jun 16, 2010 2:02:02 AM IST> <Warning> third\n"+
+			"<Jun 99, 2010 2:02:02 AM IST> <Warning> malformed\n",
+	), 0644))
+
+	searchCriteria := SearchCriteria{
+		UntilTime:      parseTime(odlTimeFormat, `Jun 16, 2010 12:00:00 AM IST`),
+		LeStartPattern: odlStartPattern,
+		LeTimeFormat:   odlTimeFormat,
+	}
+
+	var actual []string
+	exitStatus, err := ForwardSearch(logFile, &searchCriteria,
+		func(logEntry []byte) { actual = append(actual, string(logEntry)) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"<Jun 15, 2010 2:01:20 AM IST> <Error> first\ncontinued",
+		"<Jun 15, 2010 2:02:02 AM IST> <Warning> second",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT:\n%s\nWANT:\n%s", strings.Join(actual, "\n"),
+			strings.Join(expected, "\n"))
+	}
+	if exitStatus != 0 {
+		t.Errorf("Got exit status: %d, want exit status: 0", exitStatus)
+	}
+}
+
+// Red path testing of ForwardSearch
+func TestRedPathsForwardSearch(t *testing.T) {
+	StartBufLen = 256
+	origMaxBufLen := MaxBufLen
+
+	var tests = []struct {
+		name           string         // test name (also description summary)
+		filePath       string         // path to log file
+		searchCriteria SearchCriteria // searchCriteria ; 2nd param of ForwardSearch
+		expectedErr    string         // string that should be contained within the error obj
+		setUp          func()         // set up function
+		cleanUp        func()         // clean up function
+	}{
+		{
+			name:        "test 1: leStartPattern must be set",
+			filePath:    accessLog,
+			expectedErr: NoLeStartPattern,
+		},
+		{
+			name:     "test 2: bad leStartPattern for small file",
+			filePath: odlLog,
+			searchCriteria: SearchCriteria{
+				LeStartPattern: odlStartPattern + `unmatch$`,
+			},
+			expectedErr: NoLogEntriesInFile,
+		},
+		{
+			name:     "test 3: no more log entries",
+			filePath: accessLogNoMoreEntries,
+			searchCriteria: SearchCriteria{
+				LeStartPattern: apacheStartPattern,
+			},
+			expectedErr: NoMoreLogEntries,
+		},
+		{
+			name:     "test 4: leTimeFormat mismatch",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				UntilTime:      parseTime(apacheTimeFormat, `23/Sep/2019:00:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat + `unmatch`,
+			},
+			expectedErr: LeTimeFormatMismatch,
+		},
+		{
+			name:     "test 5: log entry length excedes MaxBufLen",
+			filePath: longLineLog,
+			searchCriteria: SearchCriteria{
+				LeStartPattern: odlStartPattern,
+			},
+			expectedErr: MaxBufLenReached,
+			setUp:       func() { MaxBufLen = 5000 },
+			cleanUp:     func() { MaxBufLen = origMaxBufLen },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.setUp != nil {
+				test.setUp()
+			}
+			if test.cleanUp != nil {
+				defer test.cleanUp()
+			}
+
+			exitStatus, err := ForwardSearch(test.filePath, &test.searchCriteria,
+				func(logEntry []byte) {})
+			if err == nil {
+				t.Error("No error returned")
+			} else if !strings.Contains(err.Error(), test.expectedErr) {
+				t.Errorf("Got error: \"%s\", want error that contains: \"%s\"",
+					err.Error(), test.expectedErr)
+			}
+			if exitStatus != -1 {
+				t.Errorf("Got exit status: %d, want exit status: -1", exitStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package reversesearch
+
+/* The per search options are contained in this file:
+- Options (type)
+- NewlinePolicy (type)
+- PreamblePolicy (type)
+- BadTimePolicy (type)
+- SearchStats (type)
+- badTimeState (type) and its methods
+- resolveOptions
+- getBuf (method)
+- putBuf (method)
+
+Options allow settings that would otherwise be taken from the package level
+variables (i.e. StartBufLen and MaxBufLen) to be set per search, so that
+searches running concurrently with different settings don't race.
+*/
+
+import (
+	"sync"
+	"time"
+)
+
+// NewlinePolicy determines which byte sequences are treated as newlines
+type NewlinePolicy int
+
+const (
+	// NewlineAuto treats both \n (Unix style) and \r\n (windows style) as
+	// newlines; the \r of a \r\n is not considered part of the line
+	NewlineAuto NewlinePolicy = iota
+
+	// NewlineLF only treats \n as a newline, so a \r that precedes it is
+	// considered part of the line (and of the log entry)
+	NewlineLF
+)
+
+// crlf reports whether \r\n is treated as a single newline
+func (p NewlinePolicy) crlf() bool {
+	return p != NewlineLF
+}
+
+// PreamblePolicy determines how bytes before the first log entry in a log file
+// (i.e. lines that precede the first line that matches LeStartPattern) are
+// treated. A single newline at the beginning of a log file is always allowed.
+type PreamblePolicy int
+
+const (
+	// PreambleError returns a NoMoreLogEntries error once the search reaches the
+	// preamble. Matching log entries found before then will already have been
+	// passed on.
+	PreambleError PreamblePolicy = iota
+
+	// PreambleIgnore ignores the preamble, so the search completes normally
+	PreambleIgnore
+
+	// PreambleEntry passes the preamble to the handler as a pseudo log entry
+	// (i.e. a LogEntry whose Preamble field is set), so the search completes
+	// normally. The preamble has no time of logging, so it is treated as though
+	// it was logged at the same time as the first log entry in the log file; it
+	// is only passed on if that log entry satisfies FromTime and UntilTime (a
+	// log entry that is only within Tolerance of them doesn't), and the search
+	// has reached it. Regexps and MaxEntryLen apply to it as they do to any log
+	// entry.
+	PreambleEntry
+)
+
+// BadTimePolicy determines how a log entry is treated when the time stamp in its
+// first line (i.e. the line that matches LeStartPattern) can't be parsed with
+// LeTimeFormat, e.g. because the line has been corrupted
+type BadTimePolicy int
+
+const (
+	// BadTimeAbort ends the search with a *TimeParseError
+	BadTimeAbort BadTimePolicy = iota
+
+	// BadTimeSkip skips the log entry; it is not passed to the handler, and it
+	// plays no part in the time constraints
+	BadTimeSkip
+
+	// BadTimeMerge treats the line as though it didn't match LeStartPattern, so
+	// that it becomes part of the previous log entry in the log file
+	BadTimeMerge
+
+	// BadTimeInherit gives the log entry the time of the neighbouring log entry
+	// that was traversed before it (i.e. the next log entry in the log file for a
+	// reverse search, and the previous one otherwise). If there isn't one, the log
+	// entry is skipped.
+	BadTimeInherit
+)
+
+// SearchStats holds statistics about searches (see Options.Stats)
+type SearchStats struct {
+	// BadTimes is the number of log entries whose time stamps couldn't be parsed,
+	// and which were therefore treated as Options.BadTimes dictates
+	BadTimes int
+
+	// SkippedEntries is the number of log entries that were skipped because their
+	// time stamps couldn't be parsed
+	SkippedEntries int
+}
+
+// Options holds settings that apply to a single search. A nil *Options, or zero
+// valued fields, mean the defaults are used.
+type Options struct {
+	// StartBufLen is the starting length of the bytes buffer which is used to
+	// read bytes from the log file. Defaults to the StartBufLen variable.
+	StartBufLen int
+
+	// MaxBufLen is the maximum length of the bytes buffer. Defaults to the
+	// MaxBufLen variable.
+	MaxBufLen int
+
+	// MaxEntryLen is the maximum length in bytes of a log entry. A log entry that
+	// is longer causes the search to end with a MaxEntryLenExceeded error. When
+	// not set, log entries are only limited by MaxBufLen.
+	MaxEntryLen int
+
+	// Newlines determines which byte sequences are treated as newlines. Defaults
+	// to NewlineAuto.
+	Newlines NewlinePolicy
+
+	// Preamble determines how bytes before the first log entry in the log file
+	// are treated. Defaults to PreambleError.
+	Preamble PreamblePolicy
+
+	// EmptyFileIsError causes an empty log file (or one that only holds a
+	// newline) to be reported with a FileIsEmpty error, rather than with an exit
+	// status of 1
+	EmptyFileIsError bool
+
+	// BadTimes determines how log entries whose time stamps can't be parsed are
+	// treated. Defaults to BadTimeAbort.
+	BadTimes BadTimePolicy
+
+	// YearReference is the time that time stamps without a year (e.g. BSD syslog
+	// time stamps such as "May  1 12:00:01") are anchored on when their year is
+	// inferred. Defaults to the log file's modification time, or the current time
+	// if that isn't available (e.g. when following a log file). When the log file
+	// is searched in reverse, the newest log entry is given the latest year in
+	// which it isn't after YearReference, and the year is decremented whenever
+	// the month jumps forward (e.g. from January to December) between a log
+	// entry and the log entry that precedes it. Otherwise, each log entry is given
+	// the latest year in which it isn't after YearReference.
+	YearReference time.Time
+
+	// Stats, when set, has the statistics of each search added to it once the
+	// search ends (even if it ends with an error). As the statistics are added
+	// rather than assigned, Stats accumulates across searches. It can be shared
+	// by searches that run concurrently (e.g. those of a Searcher), as they add
+	// to it under a lock, but it should only be read once they've ended.
+	Stats *SearchStats
+
+	// bufPool holds StartBufLen sized buffers for reuse across searches. It is
+	// only set on the resolved options of a Searcher.
+	bufPool *sync.Pool
+}
+
+// resolveOptions returns a copy of opts with the defaults filled in. The
+// package level variables are read at this point, so changes made to them
+// during a search have no effect on it.
+func resolveOptions(opts *Options) *Options {
+	resolved := Options{}
+	if opts != nil {
+		resolved = *opts
+	}
+	if resolved.StartBufLen <= 0 {
+		resolved.StartBufLen = StartBufLen
+	}
+	if resolved.MaxBufLen <= 0 {
+		resolved.MaxBufLen = MaxBufLen
+	}
+	if resolved.StartBufLen > resolved.MaxBufLen {
+		resolved.StartBufLen = resolved.MaxBufLen
+	}
+	resolved.bufPool = nil
+	return &resolved
+}
+
+// badTimeState applies a BadTimePolicy to the log entries of a single search,
+// counting the log entries whose time stamps couldn't be parsed
+type badTimeState struct {
+	policy BadTimePolicy
+
+	// prevTime is the time of the log entry that was last traversed, which is
+	// inherited under BadTimeInherit
+	prevTime time.Time
+
+	badTimes       int
+	skippedEntries int
+}
+
+// newBadTimeState returns a badTimeState for a search carried out with opts
+func newBadTimeState(opts *Options) *badTimeState {
+	return &badTimeState{policy: opts.BadTimes}
+}
+
+// statsMu guards the SearchStats that searches report to, as a single
+// SearchStats can be shared by concurrent searches (see Options.Stats)
+var statsMu sync.Mutex
+
+// report adds the counts to stats (if it's set)
+func (s *badTimeState) report(stats *SearchStats) {
+	if stats != nil {
+		statsMu.Lock()
+		defer statsMu.Unlock()
+		stats.BadTimes += s.badTimes
+		stats.SkippedEntries += s.skippedEntries
+	}
+}
+
+// getBuf returns a byte slice of length n, which is taken from the buffer pool
+// if there is one and n is no more than StartBufLen. Its contents are not
+// zeroed.
+func (opts *Options) getBuf(n int) []byte {
+	if opts.bufPool == nil || n > opts.StartBufLen {
+		return make([]byte, n)
+	}
+	if buf, ok := opts.bufPool.Get().(*[]byte); ok {
+		return (*buf)[:n]
+	}
+	return make([]byte, n, opts.StartBufLen)
+}
+
+// putBuf returns buf, which must have been returned by getBuf, to the buffer
+// pool (if there is one) so that it can be reused by a later search
+func (opts *Options) putBuf(buf []byte) {
+	if opts.bufPool != nil && cap(buf) == opts.StartBufLen {
+		opts.bufPool.Put(&buf)
+	}
+}
@@ -0,0 +1,424 @@
+package reversesearch_test
+
+/* Integration tests for ReverseSearchWithOptions and the settings in Options.
+Green path results are compared with the results of ReverseSearch using the
+default settings where possible. */
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/freebiesoft/reversesearch"
+)
+
+// collectEntries returns an EntryHandler that appends the log entries it is
+// passed to entries
+func collectEntries(entries *[]string) EntryHandler {
+	return func(logEntry LogEntry) (Control, error) {
+		*entries = append(*entries, string(logEntry.Bytes))
+		return Continue, nil
+	}
+}
+
+// TestReverseSearchWithOptions checks that the settings in Options apply to the
+// search, and that they don't alter the package level defaults
+func TestReverseSearchWithOptions(t *testing.T) {
+	StartBufLen = 256
+
+	var tests = []struct {
+		name               string
+		filePath           string
+		searchCriteria     SearchCriteria
+		opts               *Options
+		expectedExitStatus int
+		expectedErr        string
+	}{
+		{
+			name:     "test 1: nil options",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				Regexps:        []string{`/modules/mod_araticlhess1/mod_araticlhess1\.php`},
+				LeStartPattern: apacheStartPattern,
+			},
+		},
+		{
+			name:           "test 2: StartBufLen of 1",
+			filePath:       odlLog,
+			searchCriteria: SearchCriteria{LeStartPattern: odlStartPattern},
+			opts:           &Options{StartBufLen: 1},
+		},
+		{
+			name:           "test 3: StartBufLen more than file size",
+			filePath:       odlLog,
+			searchCriteria: SearchCriteria{LeStartPattern: odlStartPattern},
+			opts:           &Options{StartBufLen: 100000},
+		},
+		{
+			name:           "test 4: preamble ignored",
+			filePath:       accessLogNoMoreEntries,
+			searchCriteria: SearchCriteria{LeStartPattern: apacheStartPattern},
+			opts:           &Options{Preamble: PreambleIgnore},
+		},
+		{
+			name:               "test 5: empty file is not an error by default",
+			filePath:           emptyFile,
+			searchCriteria:     SearchCriteria{LeStartPattern: odlStartPattern},
+			expectedExitStatus: 1,
+		},
+		{
+			name:               "test 6: log entry length excedes MaxBufLen",
+			filePath:           longLineLog,
+			searchCriteria:     SearchCriteria{LeStartPattern: odlStartPattern},
+			opts:               &Options{MaxBufLen: 5000},
+			expectedExitStatus: -1,
+			expectedErr:        MaxBufLenReached,
+		},
+		{
+			name:               "test 7: log entry length excedes MaxEntryLen",
+			filePath:           odlLog,
+			searchCriteria:     SearchCriteria{LeStartPattern: odlStartPattern},
+			opts:               &Options{MaxEntryLen: 100},
+			expectedExitStatus: -1,
+			expectedErr:        MaxEntryLenExceeded,
+		},
+		{
+			name:               "test 8: preamble is an error",
+			filePath:           accessLogNoMoreEntries,
+			searchCriteria:     SearchCriteria{LeStartPattern: apacheStartPattern},
+			opts:               &Options{Preamble: PreambleError},
+			expectedExitStatus: -1,
+			expectedErr:        NoMoreLogEntries,
+		},
+		{
+			name:               "test 9: empty file is an error",
+			filePath:           emptyFile,
+			searchCriteria:     SearchCriteria{LeStartPattern: odlStartPattern},
+			opts:               &Options{EmptyFileIsError: true},
+			expectedExitStatus: -1,
+			expectedErr:        FileIsEmpty,
+		},
+		{
+			name:               "test 10: newline only file is an error",
+			filePath:           nlOnlyWin,
+			searchCriteria:     SearchCriteria{LeStartPattern: odlStartPattern},
+			opts:               &Options{EmptyFileIsError: true},
+			expectedExitStatus: -1,
+			expectedErr:        FileIsEmpty,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var actual []string
+			exitStatus, err := ReverseSearchWithOptions(context.Background(), test.filePath,
+				&test.searchCriteria, test.opts, collectEntries(&actual))
+
+			if exitStatus != test.expectedExitStatus {
+				t.Errorf("Got exit status: %d, want exit status: %d", exitStatus,
+					test.expectedExitStatus)
+			}
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Errorf("Got error: %v, want error that contains: \"%s\"", err,
+						test.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the log entries found before any error must be the same as those
+			// found with the default settings
+			var expected []string
+			ReverseSearch(test.filePath, &test.searchCriteria,
+				func(logEntry []byte) { expected = append(expected, string(logEntry)) })
+			if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+				t.Errorf("Got %d log entries, want %d log entries", len(actual), len(expected))
+			}
+		})
+	}
+
+	if StartBufLen != 256 {
+		t.Errorf("Got StartBufLen: %d, want StartBufLen: 256", StartBufLen)
+	}
+}
+
+// TestNewlineLF checks that with NewlineLF, the \r of a \r\n newline is kept as
+// part of the log entry that precedes it
+func TestNewlineLF(t *testing.T) {
+	StartBufLen = 256
+	searchCriteria := SearchCriteria{LeStartPattern: odlStartPattern}
+
+	var auto, lf []string
+	_, err := ReverseSearchWithOptions(context.Background(), odlLogNoNlSuffixWin,
+		&searchCriteria, &Options{Newlines: NewlineAuto}, collectEntries(&auto))
+	check(err)
+	_, err = ReverseSearchWithOptions(context.Background(), odlLogNoNlSuffixWin,
+		&searchCriteria, &Options{Newlines: NewlineLF}, collectEntries(&lf))
+	check(err)
+
+	if len(lf) != len(auto) || len(lf) < 2 {
+		t.Fatalf("Got %d log entries, want %d log entries", len(lf), len(auto))
+	}
+	// the last log entry in the file (i.e. the first found) has no newline suffix
+	for i := range lf {
+		want := auto[i]
+		if i > 0 {
+			want += "\r"
+		}
+		if lf[i] != want {
+			t.Errorf("Got log entry:\n%q\nwant log entry:\n%q", lf[i], want)
+		}
+	}
+}
+
+// TestBadTimePolicy checks that each BadTimePolicy treats a log entry whose
+// time stamp can't be parsed as expected, and that the log entry is counted
+func TestBadTimePolicy(t *testing.T) {
+	StartBufLen = 256
+	logFile := filepath.Join(t.TempDir(), "bad_time.log")
+	check(ioutil.WriteFile(logFile, []byte(followLines[0]+"\n"+
+		"<Xxx 16, 2010 2:02:02 AM IST> <Warning> corrupted\ncontinued\n"+
+		followLines[3]+"\n"), 0644))
+	corrupted := "<Xxx 16, 2010 2:02:02 AM IST> <Warning> corrupted\ncontinued"
+	searchCriteria := SearchCriteria{
+		FromTime:       parseTime(odlTimeFormat, `Jun 15, 2010 12:00:00 AM IST`),
+		LeStartPattern: odlStartPattern,
+		LeTimeFormat:   odlTimeFormat,
+	}
+
+	var tests = []struct {
+		name            string
+		badTimes        BadTimePolicy
+		forward         bool
+		expectedEntries []string
+		expectedTimes   []string // time stamps of the expected log entries
+		expectedStats   SearchStats
+		expectedErr     error
+	}{
+		{
+			name:        "test 1: abort",
+			badTimes:    BadTimeAbort,
+			expectedErr: ErrLeTimeFormatMismatch,
+		},
+		{
+			name:            "test 2: skip",
+			badTimes:        BadTimeSkip,
+			expectedEntries: []string{followLines[3], followLines[0]},
+			expectedTimes:   []string{`Jun 17, 2010 2:02:02 AM IST`, `Jun 15, 2010 2:01:20 AM IST`},
+			expectedStats:   SearchStats{BadTimes: 1, SkippedEntries: 1},
+		},
+		{
+			name:            "test 3: merge",
+			badTimes:        BadTimeMerge,
+			expectedEntries: []string{followLines[3], followLines[0] + "\n" + corrupted},
+			expectedTimes:   []string{`Jun 17, 2010 2:02:02 AM IST`, `Jun 15, 2010 2:01:20 AM IST`},
+			expectedStats:   SearchStats{BadTimes: 1},
+		},
+		{
+			name:            "test 4: inherit the next log entry's time",
+			badTimes:        BadTimeInherit,
+			expectedEntries: []string{followLines[3], corrupted, followLines[0]},
+			expectedTimes: []string{`Jun 17, 2010 2:02:02 AM IST`,
+				`Jun 17, 2010 2:02:02 AM IST`, `Jun 15, 2010 2:01:20 AM IST`},
+			expectedStats: SearchStats{BadTimes: 1},
+		},
+		{
+			name:            "test 5: inherit the previous log entry's time",
+			badTimes:        BadTimeInherit,
+			forward:         true,
+			expectedEntries: []string{followLines[0], corrupted, followLines[3]},
+			expectedTimes: []string{`Jun 15, 2010 2:01:20 AM IST`,
+				`Jun 15, 2010 2:01:20 AM IST`, `Jun 17, 2010 2:02:02 AM IST`},
+			expectedStats: SearchStats{BadTimes: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stats := SearchStats{}
+			searcher, err := NewSearcher(&searchCriteria,
+				&Options{BadTimes: test.badTimes, Stats: &stats})
+			check(err)
+
+			var entries, times []string
+			handler := func(logEntry LogEntry) (Control, error) {
+				entries = append(entries, string(logEntry.Bytes))
+				times = append(times, logEntry.Time.Format(odlTimeFormat))
+				return Continue, nil
+			}
+			if test.forward {
+				_, err = searcher.ForwardSearch(logFile, handler)
+			} else {
+				_, err = searcher.ReverseSearch(context.Background(), logFile, handler)
+			}
+
+			if test.expectedErr != nil {
+				if !errors.Is(err, test.expectedErr) {
+					t.Errorf("Got error: %v, want error that is: %v", err, test.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entries, test.expectedEntries) {
+				t.Errorf("GOT:\n%q\nWANT:\n%q", entries, test.expectedEntries)
+			}
+			if !reflect.DeepEqual(times, test.expectedTimes) {
+				t.Errorf("Got times: %q, want times: %q", times, test.expectedTimes)
+			}
+			if stats != test.expectedStats {
+				t.Errorf("Got stats: %+v, want stats: %+v", stats, test.expectedStats)
+			}
+		})
+	}
+}
+
+// TestPreambleEntry checks that with PreambleEntry, the bytes before the first
+// log entry are passed to the handler as a pseudo log entry, in the position
+// that the search order dictates
+func TestPreambleEntry(t *testing.T) {
+	StartBufLen = 256
+	contents, err := ioutil.ReadFile(accessLogNoMoreEntries)
+	check(err)
+	preamble := string(contents[:bytes.Index(contents, []byte("\n54.37.76.123"))])
+
+	var tests = []struct {
+		name             string
+		forward          bool
+		searchCriteria   SearchCriteria
+		expectedPreamble bool
+	}{
+		{
+			name:             "test 1: reverse search",
+			searchCriteria:   SearchCriteria{LeStartPattern: apacheStartPattern},
+			expectedPreamble: true,
+		},
+		{
+			name:             "test 2: forward search",
+			forward:          true,
+			searchCriteria:   SearchCriteria{LeStartPattern: apacheStartPattern},
+			expectedPreamble: true,
+		},
+		{
+			name:    "test 3: preamble doesn't match regexps",
+			forward: true,
+			searchCriteria: SearchCriteria{
+				Regexps:        []string{`54\.37\.76\.123`},
+				LeStartPattern: apacheStartPattern,
+			},
+		},
+		{
+			name: "test 4: reverse search, first log entry only within tolerance of fromTime",
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(apacheTimeFormat, `21/Sep/2019:20:34:30 +0200`),
+				Tolerance:      time.Minute,
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+		},
+		{
+			name:    "test 5: forward search, first log entry only within tolerance of fromTime",
+			forward: true,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(apacheTimeFormat, `21/Sep/2019:20:34:30 +0200`),
+				Tolerance:      time.Minute,
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+		},
+		{
+			name: "test 6: reverse search, first log entry fails untilTime",
+			searchCriteria: SearchCriteria{
+				UntilTime:      parseTime(apacheTimeFormat, `21/Sep/2019:20:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+		},
+		{
+			name:    "test 7: forward search, first log entry fails untilTime",
+			forward: true,
+			searchCriteria: SearchCriteria{
+				UntilTime:      parseTime(apacheTimeFormat, `21/Sep/2019:20:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the log entries found should be those found when the preamble is ignored
+			ignoreSearcher, err := NewSearcher(&test.searchCriteria,
+				&Options{Preamble: PreambleIgnore})
+			check(err)
+			searcher, err := NewSearcher(&test.searchCriteria, &Options{Preamble: PreambleEntry})
+			check(err)
+
+			var expected, actual []string
+			var preambles []LogEntry
+			handler := func(logEntry LogEntry) (Control, error) {
+				if logEntry.Preamble {
+					// logEntry.Bytes is only valid until the handler returns
+					logEntry.Bytes = append([]byte(nil), logEntry.Bytes...)
+					preambles = append(preambles, logEntry)
+				}
+				actual = append(actual, string(logEntry.Bytes))
+				return Continue, nil
+			}
+			if test.forward {
+				_, err = ignoreSearcher.ForwardSearch(accessLogNoMoreEntries,
+					collectEntries(&expected))
+				check(err)
+				_, err = searcher.ForwardSearch(accessLogNoMoreEntries, handler)
+			} else {
+				_, err = ignoreSearcher.ReverseSearch(context.Background(),
+					accessLogNoMoreEntries, collectEntries(&expected))
+				check(err)
+				_, err = searcher.ReverseSearch(context.Background(), accessLogNoMoreEntries,
+					handler)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !test.expectedPreamble {
+				if len(preambles) != 0 {
+					t.Fatalf("Got %d preambles, want none", len(preambles))
+				}
+				if !reflect.DeepEqual(actual, expected) {
+					t.Errorf("Got %d log entries, want %d log entries", len(actual),
+						len(expected))
+				}
+				return
+			}
+
+			if len(preambles) != 1 {
+				t.Fatalf("Got %d preambles, want 1", len(preambles))
+			}
+			if string(preambles[0].Bytes) != preamble || preambles[0].Offset != 0 ||
+				!preambles[0].Time.IsZero() {
+				t.Errorf("Got preamble: %q at %d, want preamble: %q at 0",
+					preambles[0].Bytes, preambles[0].Offset, preamble)
+			}
+			// the preamble is the oldest "log entry" in the file
+			if test.forward {
+				expected = append([]string{preamble}, expected...)
+			} else {
+				expected = append(expected, preamble)
+			}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Got %d log entries, want %d log entries", len(actual), len(expected))
+			}
+		})
+	}
+}
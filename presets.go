@@ -0,0 +1,192 @@
+package reversesearch
+
+/* The registry of log format presets is contained in this file:
+- Preset (type)
+- preset names (e.g. PresetApache)
+- RegisterPreset (exported)
+- LookupPreset (exported)
+- PresetNames (exported)
+- ApplyPreset (exported)
+
+A preset holds the LeStartPattern and LeTimeFormat of a common log format, so
+that they don't have to be written by hand. The built-in presets are registered
+when the package is initialised, and custom presets can be registered at
+runtime.
+*/
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Preset holds the search criteria fields that describe a log format
+type Preset struct {
+	// LeStartPattern matches the beginning of the format's log entries, and
+	// captures their time stamp (see SearchCriteria.LeStartPattern)
+	LeStartPattern string
+
+	// LeTimeFormat is the layout of the format's time stamps (see
+	// SearchCriteria.LeTimeFormat)
+	LeTimeFormat string
+}
+
+// names of the built-in presets. Time stamps without a time zone (e.g. those of
+// PresetPython) are parsed in UTC unless SearchCriteria.LeTimeLocation is set,
+// and time stamps without a year (e.g. those of PresetRFC3164) have their year
+// inferred (see Options.YearReference).
+const (
+	// PresetApache is the Apache and Nginx combined (or common) access log
+	// format, e.g. `127.0.0.1 - - [01/May/2024:12:00:00 +0000] "GET / HTTP/1.1" 200 512`
+	PresetApache = "apache"
+
+	// PresetODL is the Oracle Diagnostic Logging format, e.g.
+	// `<Jun 17, 2010 11:00:00 PM IST> <Error> <oracle.iam> <IAM-1010032> ...`.
+	// A time stamp whose zone abbreviation isn't recognised (e.g. IST) is parsed
+	// at offset 0 unless SearchCriteria.LeTimeZones is set.
+	PresetODL = "odl"
+
+	// PresetRFC3164 is the BSD syslog format, with or without its priority, e.g.
+	// `<34>May  1 12:00:00 host su: ...`
+	PresetRFC3164 = "rfc3164"
+
+	// PresetRFC5424 is the IETF syslog format, e.g.
+	// `<165>1 2024-05-01T12:00:00.003Z host app 1234 ID47 - ...`
+	PresetRFC5424 = "rfc5424"
+
+	// PresetLog4j is the ISO 8601 date layout of log4j and logback, e.g.
+	// `2024-05-01 12:00:00,123 [main] INFO  com.example.App - ...`
+	PresetLog4j = "log4j"
+
+	// PresetPython is Python logging's asctime, e.g.
+	// `2024-05-01 12:00:00,123 - app - INFO - ...`
+	PresetPython = "python"
+
+	// PresetGoLog is the standard Go logger's format (log.LstdFlags, with or
+	// without log.Lmicroseconds), e.g. `2024/05/01 12:00:00 ...`
+	PresetGoLog = "golog"
+
+	// PresetSlog is the Go log/slog text handler's format, e.g.
+	// `time=2024-05-01T12:00:00.000Z level=INFO msg=...`
+	PresetSlog = "slog"
+
+	// PresetKlog is the Kubernetes klog format, e.g.
+	// `I0501 12:00:00.123456    1234 main.go:42] ...`
+	PresetKlog = "klog"
+
+	// PresetPostgreSQL is the PostgreSQL server log format with the default
+	// log_line_prefix ('%m [%p] '), e.g. `2024-05-01 12:00:00.123 UTC [1234] LOG:  ...`.
+	// A time stamp whose zone abbreviation isn't recognised (e.g. CEST) is parsed
+	// at offset 0 unless SearchCriteria.LeTimeZones is set.
+	PresetPostgreSQL = "postgresql"
+)
+
+// presets is the registry of presets, keyed by name
+var presets = map[string]Preset{
+	PresetApache: {
+		LeStartPattern: `^(?:\S+) (?:\S+) (?:\S+) \[([\w:/]+\s[+\-]\d{4})\]`,
+		LeTimeFormat:   `02/Jan/2006:15:04:05 -0700`,
+	},
+	PresetODL: {
+		LeStartPattern: `^<(\w{3} \d{1,2}, \d{4} \d{1,2}:\d{2}:\d{2} (?:AM|PM) (?:\S+))>`,
+		LeTimeFormat:   `Jan 2, 2006 3:04:05 PM MST`,
+	},
+	PresetRFC3164: {
+		LeStartPattern: `^(?:<\d{1,3}>)?(\w{3} [ \d]\d \d{2}:\d{2}:\d{2}) `,
+		LeTimeFormat:   `Jan _2 15:04:05`,
+	},
+	PresetRFC5424: {
+		LeStartPattern: `^<\d{1,3}>\d{1,2} (\S+) `,
+		LeTimeFormat:   time.RFC3339Nano,
+	},
+	PresetLog4j: {
+		LeStartPattern: `^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:[,.]\d+)?) `,
+		LeTimeFormat:   `2006-01-02 15:04:05`,
+	},
+	PresetPython: {
+		LeStartPattern: `^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2},\d{3}) `,
+		LeTimeFormat:   `2006-01-02 15:04:05,000`,
+	},
+	PresetGoLog: {
+		LeStartPattern: `^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)?) `,
+		LeTimeFormat:   `2006/01/02 15:04:05`,
+	},
+	PresetSlog: {
+		LeStartPattern: `^time=(?P<ts>\S+) level=(?P<level>\S+)`,
+		LeTimeFormat:   time.RFC3339Nano,
+	},
+	PresetKlog: {
+		LeStartPattern: `^(?P<severity>[IWEF])(?P<ts>\d{4} \d{2}:\d{2}:\d{2}\.\d{6}) `,
+		LeTimeFormat:   `0102 15:04:05.000000`,
+	},
+	PresetPostgreSQL: {
+		LeStartPattern: `^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)? \S+) \[\d+\]`,
+		LeTimeFormat:   `2006-01-02 15:04:05 MST`,
+	},
+}
+
+// presetsMu guards presets, as presets can be registered while searches are
+// being set up in other goroutines
+var presetsMu sync.RWMutex
+
+// RegisterPreset adds a preset to the registry under name, so that it can be
+// used with ApplyPreset. A preset that is already registered under name
+// (including a built-in preset) is replaced. An error is returned if name is
+// empty, or if preset's LeStartPattern is empty or won't compile.
+func RegisterPreset(name string, preset Preset) error {
+	if name == "" {
+		return ErrBadPreset
+	}
+	if preset.LeStartPattern == "" {
+		return ErrNoLeStartPattern
+	}
+	if _, err := regexp.Compile(preset.LeStartPattern); err != nil {
+		if strings.Contains(err.Error(), `error parsing regexp`) {
+			return ErrBadLeStartPattern
+		}
+		return err
+	}
+
+	presetsMu.Lock()
+	defer presetsMu.Unlock()
+	presets[name] = preset
+	return nil
+}
+
+// LookupPreset returns the preset registered under name, and whether there is
+// one
+func LookupPreset(name string) (Preset, bool) {
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+	preset, ok := presets[name]
+	return preset, ok
+}
+
+// PresetNames returns the names of the registered presets in alphabetical order
+func PresetNames() []string {
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyPreset sets searchCriteria's LeStartPattern and LeTimeFormat to those of
+// the preset registered under name. The other fields of searchCriteria (e.g.
+// FromTime) are left as they are. An error that matches ErrUnknownPreset is
+// returned if there is no such preset.
+func ApplyPreset(searchCriteria *SearchCriteria, name string) error {
+	preset, ok := LookupPreset(name)
+	if !ok {
+		return fmt.Errorf("%w, %q", ErrUnknownPreset, name)
+	}
+	searchCriteria.LeStartPattern = preset.LeStartPattern
+	searchCriteria.LeTimeFormat = preset.LeTimeFormat
+	return nil
+}
@@ -0,0 +1,218 @@
+package reversesearch_test
+
+/* Integration tests for the registry of log format presets. Each built-in
+preset is checked against log entries written by the test in its format. */
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/freebiesoft/reversesearch"
+)
+
+// TestBuiltInPresets checks that each built-in preset delimits log entries in
+// its format, and parses their time stamps
+func TestBuiltInPresets(t *testing.T) {
+	StartBufLen = 256
+	yearReference := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		name          string
+		preset        string
+		lines         []string
+		expectedTimes []string // the log entries' times, in RFC3339 format
+	}{
+		{
+			name:   "test 1: apache",
+			preset: PresetApache,
+			lines: []string{
+				`127.0.0.1 - - [01/May/2024:12:00:00 +0200] "GET / HTTP/1.1" 200 512 "-" "curl/8.0"`,
+				`127.0.0.1 - frank [01/May/2024:12:00:01 +0200] "GET /a HTTP/1.1" 404 0 "-" "curl/8.0"`,
+			},
+			expectedTimes: []string{"2024-05-01T12:00:00+02:00", "2024-05-01T12:00:01+02:00"},
+		},
+		{
+			name:   "test 2: odl",
+			preset: PresetODL,
+			lines: []string{
+				"<Jun 7, 2010 11:00:00 PM UTC> <Error> <oracle.iam> <IAM-1010032>\ndetails",
+				"<Jun 17, 2010 1:02:03 AM UTC> <Info> <oracle.iam> <IAM-1010033>",
+			},
+			expectedTimes: []string{"2010-06-07T23:00:00Z", "2010-06-17T01:02:03Z"},
+		},
+		{
+			name:   "test 3: rfc3164",
+			preset: PresetRFC3164,
+			lines: []string{
+				"<34>May  1 12:00:00 host su: 'su root' failed for frank",
+				"May 11 12:00:01 host sshd[1234]: Accepted publickey",
+			},
+			expectedTimes: []string{"2024-05-01T12:00:00Z", "2024-05-11T12:00:01Z"},
+		},
+		{
+			name:   "test 4: rfc5424",
+			preset: PresetRFC5424,
+			lines: []string{
+				"<165>1 2024-05-01T12:00:00.003Z host app 1234 ID47 - started",
+				`<165>1 2024-05-01T14:00:01+02:00 host app 1234 ID47 [exampleSDID@32473 iut="3"] done`,
+			},
+			expectedTimes: []string{"2024-05-01T12:00:00Z", "2024-05-01T14:00:01+02:00"},
+		},
+		{
+			name:   "test 5: log4j",
+			preset: PresetLog4j,
+			lines: []string{
+				"2024-05-01 12:00:00,123 [main] INFO  com.example.App - started",
+				"2024-05-01 12:00:01.456 [main] ERROR com.example.App - failed\n\tat com.example.App.run",
+			},
+			expectedTimes: []string{"2024-05-01T12:00:00Z", "2024-05-01T12:00:01Z"},
+		},
+		{
+			name:   "test 6: python",
+			preset: PresetPython,
+			lines: []string{
+				"2024-05-01 12:00:00,123 - app - INFO - started",
+				"2024-05-01 12:00:01,456 - app - ERROR - failed\nTraceback (most recent call last):",
+			},
+			expectedTimes: []string{"2024-05-01T12:00:00Z", "2024-05-01T12:00:01Z"},
+		},
+		{
+			name:   "test 7: golog",
+			preset: PresetGoLog,
+			lines: []string{
+				"2024/05/01 12:00:00 started",
+				"2024/05/01 12:00:01.123456 stopped",
+			},
+			expectedTimes: []string{"2024-05-01T12:00:00Z", "2024-05-01T12:00:01Z"},
+		},
+		{
+			name:   "test 8: slog",
+			preset: PresetSlog,
+			lines: []string{
+				"time=2024-05-01T12:00:00.000Z level=INFO msg=started",
+				`time=2024-05-01T14:00:01.000+02:00 level=WARN msg="slow request" ms=1200`,
+			},
+			expectedTimes: []string{"2024-05-01T12:00:00Z", "2024-05-01T14:00:01+02:00"},
+		},
+		{
+			name:   "test 9: klog",
+			preset: PresetKlog,
+			lines: []string{
+				"I0501 12:00:00.123456    1234 main.go:42] started",
+				"E0501 12:00:01.000001    1234 main.go:43] failed",
+			},
+			expectedTimes: []string{"2024-05-01T12:00:00Z", "2024-05-01T12:00:01Z"},
+		},
+		{
+			name:   "test 10: postgresql",
+			preset: PresetPostgreSQL,
+			lines: []string{
+				"2024-05-01 12:00:00.123 UTC [1234] LOG:  database system is ready",
+				"2024-05-01 12:00:01.456 UTC [1240] ERROR:  relation \"a\" does not exist\n" +
+					"2024-05-01 12:00:01.456 UTC [1240] STATEMENT:  SELECT * FROM a;",
+			},
+			expectedTimes: []string{"2024-05-01T12:00:00Z", "2024-05-01T12:00:01Z", "2024-05-01T12:00:01Z"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logFile := filepath.Join(t.TempDir(), test.preset+".log")
+			check(ioutil.WriteFile(logFile, []byte(strings.Join(test.lines, "\n")+"\n"), 0644))
+
+			searchCriteria := SearchCriteria{
+				FromTime: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+			}
+			check(ApplyPreset(&searchCriteria, test.preset))
+			searcher, err := NewSearcher(&searchCriteria, &Options{YearReference: yearReference})
+			check(err)
+
+			var times []string
+			_, err = searcher.ForwardSearch(logFile, func(logEntry LogEntry) (Control, error) {
+				times = append(times, logEntry.Time.Format(time.RFC3339))
+				return Continue, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(times, test.expectedTimes) {
+				t.Errorf("Got times: %q, want times: %q", times, test.expectedTimes)
+			}
+		})
+	}
+}
+
+// TestRegisterPreset checks that custom presets can be registered and applied,
+// and that invalid presets and unknown names are rejected
+func TestRegisterPreset(t *testing.T) {
+	custom := Preset{
+		LeStartPattern: `^\[(\d{2}:\d{2}:\d{2} \d{2}\.\d{2}\.\d{4})\]`,
+		LeTimeFormat:   `15:04:05 02.01.2006`,
+	}
+	check(RegisterPreset("custom", custom))
+
+	if preset, ok := LookupPreset("custom"); !ok || preset != custom {
+		t.Errorf("Got preset: %+v (%t), want preset: %+v", preset, ok, custom)
+	}
+	names := PresetNames()
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("Got names: %q, want sorted names", names)
+	}
+	for _, name := range []string{"custom", PresetApache, PresetPostgreSQL} {
+		if i := sort.SearchStrings(names, name); i == len(names) || names[i] != name {
+			t.Errorf("Got names: %q, want names that include: %q", names, name)
+		}
+	}
+
+	searchCriteria := SearchCriteria{Regexps: []string{"error"}}
+	check(ApplyPreset(&searchCriteria, "custom"))
+	expected := SearchCriteria{
+		LeStartPattern: custom.LeStartPattern,
+		LeTimeFormat:   custom.LeTimeFormat,
+		Regexps:        []string{"error"},
+	}
+	if !reflect.DeepEqual(searchCriteria, expected) {
+		t.Errorf("Got search criteria: %+v, want search criteria: %+v", searchCriteria, expected)
+	}
+
+	var tests = []struct {
+		name        string
+		err         error
+		expectedErr error
+	}{
+		{
+			name:        "test 1: unknown preset",
+			err:         ApplyPreset(&searchCriteria, "does not exist"),
+			expectedErr: ErrUnknownPreset,
+		},
+		{
+			name:        "test 2: preset without a name",
+			err:         RegisterPreset("", custom),
+			expectedErr: ErrBadPreset,
+		},
+		{
+			name:        "test 3: preset without LeStartPattern",
+			err:         RegisterPreset("empty", Preset{LeTimeFormat: custom.LeTimeFormat}),
+			expectedErr: ErrNoLeStartPattern,
+		},
+		{
+			name:        "test 4: preset whose LeStartPattern won't compile",
+			err:         RegisterPreset("bad", Preset{LeStartPattern: `^(\d+`}),
+			expectedErr: ErrBadLeStartPattern,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !errors.Is(test.err, test.expectedErr) {
+				t.Errorf("Got error: %v, want error that is: %v", test.err, test.expectedErr)
+			}
+		})
+	}
+}
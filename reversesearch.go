@@ -8,9 +8,22 @@ package reversesearch
 
 /* All the main functions are contained in this file:
 - increaseBufLen
+- leFields
 - processLogEntry
+- processPreamble
 - processLine
 - findLogEntries
+- validateSearchCriteria
+- compileSearchCriteria
+- readAt
+- trimTrailingNewline
+- reverseSearch
+- ReverseSearchReaderAtContext (exported)
+- ReverseSearchReaderAt (exported)
+- ReverseSearchControl (exported)
+- ReverseSearchLogEntries (exported)
+- ReverseSearchWithOptions (exported)
+- ReverseSearchContext (exported)
 - ReverseSearch (exported)
 
 There are also 2 exported variables in this file:
@@ -19,12 +32,13 @@ There are also 2 exported variables in this file:
 */
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/golang-collections/collections/stack"
-	"os"
+	"io"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -32,12 +46,16 @@ import (
 // MaxBufLen defines the maximum size of the bytes buffer that is used to read
 // log files with. This number should be large enough to fit the largest log
 // entry that you might work with but not too large so that there could be a
-// chance of using too much memory.
+// chance of using too much memory. It is the default for Options.MaxBufLen, and
+// should not be changed while searches are running (set Options.MaxBufLen
+// instead).
 var MaxBufLen = 2000000 // 2MB
 
 // StartBufLen defines the starting length of the bytes buffer which is used to
 // read bytes from log files. It is advised that this value is sufficietly large
-// so as to reduce the number of file accesses and hence boost performance.
+// so as to reduce the number of file accesses and hence boost performance. It is
+// the default for Options.StartBufLen, and should not be changed while searches
+// are running (set Options.StartBufLen instead).
 var StartBufLen = 25000 // default is 25KB
 
 // OutputHandler is an interface for functions that can optionally be provided
@@ -48,6 +66,73 @@ var StartBufLen = 25000 // default is 25KB
 // be passed to that function as they're discovered.
 type OutputHandler func(logEntry []byte)
 
+// Control is returned by a ControlHandler to tell the search whether it should
+// carry on
+type Control int
+
+const (
+	// Continue carries on with the search
+	Continue Control = iota
+
+	// Stop ends the search immediately, e.g. once enough log entries have been
+	// found. The search is reported as having completed normally.
+	Stop
+)
+
+// ControlHandler is an alternative to OutputHandler for functions that need
+// to be able to end the search early (see ReverseSearchControl). Matching log
+// entries are passed to the handler as they're discovered, and the search ends
+// as soon as it returns Stop or a non nil error; in the latter case the error
+// is returned by the search function.
+type ControlHandler func(logEntry []byte) (Control, error)
+
+// LogEntry describes a matching log entry that is passed to an EntryHandler
+type LogEntry struct {
+	// Bytes holds the log entry, without its trailing newline. Bytes refers to
+	// the search's buffer, so it is only valid until the handler returns; it must
+	// be copied if it's needed afterwards.
+	Bytes []byte
+
+	// Time is the log entry's time of logging, parsed from the first line of the
+	// log entry with SearchCriteria.LeTimeFormat. It is the zero time if
+	// LeTimeFormat is not set, or if the time stamp can't be parsed and the
+	// search has no time constraints (in which case the log entry is still
+	// matched).
+	Time time.Time
+
+	// Offset is the absolute byte offset of the first byte of the log entry
+	// within the log file
+	Offset int64
+
+	// Len is the length of the log entry in bytes (i.e. len(Bytes))
+	Len int
+
+	// Lines is the number of lines that make up the log entry
+	Lines int
+
+	// Fields holds the values captured by the named capturing groups of
+	// LeStartPattern (or of the pattern of LeStartPatterns that matched), other
+	// than the time stamp's "ts" group, in the first line of the log entry, keyed
+	// by group name. Groups that didn't participate in the
+	// match are left out. It is nil if LeStartPattern (or LeStartPatterns) has no
+	// such groups.
+	Fields map[string]string
+
+	// Preamble indicates that this is not a log entry, but the bytes before the
+	// first log entry in the log file (see PreambleEntry). Its Offset is 0.
+	Preamble bool
+
+	// Pattern is the index in SearchCriteria.LeStartPatterns of the start pattern
+	// that matched the first line of the log entry. It is 0 if LeStartPattern is
+	// used instead, and -1 for the preamble.
+	Pattern int
+}
+
+// EntryHandler is an alternative to ControlHandler for functions that need
+// more than the bytes of each matching log entry (see ReverseSearchLogEntries).
+// Its return values are handled in the same way as those of a ControlHandler.
+type EntryHandler func(logEntry LogEntry) (Control, error)
+
 // SearchCriteria is a struct that defines the search criteria that is passed
 // to ReverseSearch. ReverseSearch then uses this search criteria to search the
 // log file passed to it for matching log entries. Please see examples/main.go
@@ -64,39 +149,98 @@ type SearchCriteria struct {
 	// A performant side effect of this field being set is that the moment the code
 	// detects that a log entry was logged before FromTime, it will abort any further
 	// processing of the log file, since the assumption is that log entries are
-	// logged in chronological order
+	// logged in chronological order (see Tolerance if they're not)
 	FromTime time.Time
 
 	// UntilTime is an optional field that can be set by the user. When set, all
 	// matching log entries' time stamps must be less than this time.
 	UntilTime time.Time
 
+	// Tolerance is an optional field for log files whose log entries are not
+	// strictly in chronological order (e.g. access logs whose time stamps are the
+	// time each request started). When set, the search isn't aborted upon
+	// finding a log entry logged before FromTime, but upon finding one logged
+	// before FromTime minus Tolerance (and likewise, searches that end upon
+	// reaching UntilTime end upon finding a log entry logged at or after
+	// UntilTime plus Tolerance). Log entries outside of the time constraints are
+	// still never passed on. It must not be negative.
+	Tolerance time.Duration
+
 	// LeStartPattern is the only mandatory field of this struct for ReverseSearch
 	// to operate with minimal search criteria. This string must represent a valid
 	// golang regular expression which (generally) matches the beginning of all log
 	// entries in the log file being searched. This is required so that the code can
 	// distinguish where each log entry begins and ends. If time constraints are
 	// included within the search criteria, then the timestamp of the log entry must
-	// be captured within the first capturing group of the regexp.
+	// be captured within the first (and only) capturing group of the regexp, or
+	// within a capturing group named "ts" (e.g. `^(?P<ts>\S+) (?P<level>\w+)`), in
+	// which case there can be any number of other capturing groups. The values
+	// captured by the other named groups are passed on in LogEntry.Fields.
 	LeStartPattern string
 
+	// LeStartPatterns can be set instead of LeStartPattern for log files that
+	// interleave the log entries of several writers, each with its own format
+	// (e.g. application log entries and JVM GC log entries). Each pattern has the
+	// same requirements as LeStartPattern and can have its own time stamp layout.
+	// A line is the start of a log entry if any of the patterns matches it, and
+	// if several do, the first of them is used. The index of the pattern that
+	// matched is passed on in LogEntry.Pattern.
+	LeStartPatterns []StartPattern
+
 	// LeTimeFormat represents the golang time format of the log file's log entries'
 	// timestamps. This field is required only when at least one of FromTime or
-	// UntilTime are set. This will be used to parse the string in the first
+	// UntilTime (or LeTimeTemplate) are set. This will be used to parse the string in the first
 	// capturing group of LeStartPattern's match to a time.Time struct. More information
 	// can be found about time formats here https://golang.org/pkg/time/#pkg-constants.
+	// Time stamps that are Unix epoch values can be parsed by setting LeTimeFormat
+	// to one of the epoch time formats instead (e.g. EpochSeconds, EpochMillis).
 	LeTimeFormat string
+
+	// LeTimeFormats is an optional, ordered list of further layouts for log files
+	// whose time stamp format changes part way through (e.g. after an upgrade).
+	// Time stamps are parsed with LeTimeFormat (if it's set) and then each of
+	// LeTimeFormats in turn, until one of them succeeds. The layout that last
+	// succeeded is tried first for the next time stamp, so that the common case of
+	// consecutive log entries having the same format stays fast. Wherever
+	// LeTimeFormat is required, LeTimeFormats can be set instead.
+	LeTimeFormats []string
+
+	// LeTimeTemplate is an optional field for log files whose time stamps aren't
+	// contiguous, e.g. `2024-05-01 | 12:03:44.123`. When set, the time stamp is
+	// assembled by expanding the template with LeStartPattern's capturing groups
+	// (see regexp.Regexp.Expand), and LeTimeFormat is then the layout of the
+	// assembled time stamp. For example, LeStartPattern
+	// `^(?P<date>\S+) \| (?P<time>\S+)` with LeTimeTemplate `${date} ${time}` and
+	// LeTimeFormat `2006-01-02 15:04:05.000`. LeTimeFormat must be set along with it.
+	LeTimeTemplate string
+
+	// LeTimeLocation is an optional field that sets the location that time stamps
+	// without a time zone are parsed in (as with time.ParseInLocation); it
+	// defaults to UTC. Zone abbreviations in time stamps are also resolved
+	// against it. If it's set and LeTimeFormat has no time zone, FromTime and
+	// UntilTime must have the same offset as LeTimeLocation, otherwise a
+	// TimeZoneMismatch error is returned.
+	LeTimeLocation *time.Location
+
+	// LeTimeZones is an optional field that maps zone abbreviations found in
+	// time stamps (e.g. "IST") to the locations they stand for, as abbreviations
+	// are ambiguous and time.Parse only knows those of the local time zone. If
+	// FromTime or UntilTime has a zone abbreviation in LeTimeZones, it must have
+	// the same offset as the location, otherwise a TimeZoneMismatch error is
+	// returned.
+	LeTimeZones map[string]*time.Location
 }
 
 // increaseBufLen increases the length of the bytes buffer and returns the number
 // of elements added, and an error if one is encountered. After the increase,
 // the existing elements in buf will be shifted rightwards as much as possible
 // in the same order (so that the next elements from the file can be read into the
-// buffer in relative order to the shifted elements.)
-func increaseBufLen(buf *[]byte) (int, error) {
+// buffer in relative order to the shifted elements.) The buffer's length will
+// not exceed maxBufLen.
+func increaseBufLen(buf *[]byte, maxBufLen int) (int, error) {
 	// throw an error if maximum buffer length has already been reached
-	if len(*buf) >= MaxBufLen {
-		return 0, errors.New(MaxBufLenReached)
+	if len(*buf) >= maxBufLen {
+		return 0, ErrMaxBufLenReached
 	}
 
 	// determine new buf length
@@ -105,8 +249,8 @@ func increaseBufLen(buf *[]byte) (int, error) {
 		newBufLen = 1
 	} else {
 		newBufLen = len(*buf) * 2
-		if newBufLen > MaxBufLen {
-			newBufLen = MaxBufLen
+		if newBufLen > maxBufLen {
+			newBufLen = maxBufLen
 		}
 	}
 
@@ -125,68 +269,159 @@ func increaseBufLen(buf *[]byte) (int, error) {
 	return nAdded, nil
 }
 
+// firstLine returns the first line of logEntry (i.e. the line that matched
+// leStartRegexp), without its newline
+func firstLine(logEntry []byte) []byte {
+	if nlIndex := bytes.IndexByte(logEntry, '\n'); nlIndex >= 0 {
+		return bytes.TrimSuffix(logEntry[:nlIndex], []byte{'\r'})
+	}
+	return logEntry
+}
+
+// leFields returns the values captured by the named capturing groups of
+// leStartRegexp (other than leTimeGroup and lePatternGroup) in the first line
+// of logEntry, keyed by group name. nil is returned if leStartRegexp is nil, or
+// if it has no such groups.
+func leFields(leStartRegexp *regexp.Regexp, logEntry []byte) map[string]string {
+	if leStartRegexp == nil {
+		return nil
+	}
+	names := leStartRegexp.SubexpNames()
+	hasFields := false
+	for _, name := range names {
+		if name != "" && name != leTimeGroup && name != lePatternGroup {
+			hasFields = true
+			break
+		}
+	}
+	if !hasFields {
+		return nil
+	}
+
+	line := firstLine(logEntry)
+	matchIndexes := leStartRegexp.FindSubmatchIndex(line)
+	if matchIndexes == nil { // sanity check
+		return nil
+	}
+
+	fields := make(map[string]string)
+	for i, name := range names {
+		if name == "" || name == leTimeGroup || name == lePatternGroup ||
+			matchIndexes[2*i] < 0 {
+			continue
+		}
+		fields[name] = string(line[matchIndexes[2*i]:matchIndexes[2*i+1]])
+	}
+	return fields
+}
+
 // processLogEntry takes a byte slice representing a log entry, and if all the
-// regexps in the "regexps" param match the logEntry, then the logEntry is considered
-// a match and passed to outputHandler
-func processLogEntry(logEntry []byte, regexps []*regexp.Regexp, outputHandler OutputHandler) {
+// regexps in the "regexps" param match the logEntry, then the logEntry is
+// considered a match and passed to handler, along with leTime (its time of
+// logging), leOffset (its position in the log file), the fields captured by
+// leStartRegexp's named capturing groups (see leFields) and the start pattern
+// that matched (see lePatternIndex). handler's return values are returned, or
+// Continue if logEntry is not a match. If maxEntryLen is set and logEntry is
+// longer, an error is returned instead.
+func processLogEntry(logEntry []byte, leTime time.Time, leOffset int64, maxEntryLen int,
+	leStartRegexp *regexp.Regexp, regexps []*regexp.Regexp, handler EntryHandler) (Control, error) {
+	if maxEntryLen > 0 && len(logEntry) > maxEntryLen {
+		return Continue, fmt.Errorf("%w, log entry found at %d", ErrMaxEntryLenExceeded, leOffset)
+	}
 	if regexps != nil {
 		for _, re := range regexps {
 			if !re.Match(logEntry) {
-				return
+				return Continue, nil
 			}
 		}
 	}
-	outputHandler(logEntry)
+	return handler(LogEntry{
+		Bytes:   logEntry,
+		Time:    leTime,
+		Offset:  leOffset,
+		Len:     len(logEntry),
+		Lines:   bytes.Count(logEntry, []byte{'\n'}) + 1,
+		Fields:  leFields(leStartRegexp, logEntry),
+		Pattern: lePatternIndex(leStartRegexp, logEntry),
+	})
+}
+
+// processPreamble passes preamble (the bytes before the first log entry in the
+// log file) to processLogEntry as a pseudo log entry, whose Preamble field is
+// set, whose Pattern is -1 and whose Time is the zero time. Its return values
+// are those of processLogEntry.
+func processPreamble(preamble []byte, maxEntryLen int, regexps []*regexp.Regexp,
+	handler EntryHandler) (Control, error) {
+	return processLogEntry(preamble, time.Time{}, 0, maxEntryLen, nil, regexps,
+		func(logEntry LogEntry) (Control, error) {
+			logEntry.Preamble = true
+			logEntry.Pattern = -1
+			return handler(logEntry)
+		})
 }
 
+// errSkipLogEntry is returned by processLine when a log entry should be skipped
+// because its time stamp couldn't be parsed (see BadTimeSkip)
+var errSkipLogEntry = errors.New("log entry skipped")
+
 // processLine checks to see if "line" param matches leStartRegexp. If it does,
-// and at least one of fromTime or untilTime are not nil, it will infer the
-// time of logging from the leStartRegexp's match's first capturing group,
-// and then compare this time with fromTime and untilTime. The return values are:
+// and timeParser is not nil (i.e. LeTimeFormat or LeTimeFormats is set), it
+// will infer the time of logging from leStartRegexp's match, and then compare
+// this time with fromTime and untilTime. The return values are:
 // 1) startOfLe (bool): indicates if the line matches leStartRegexp
 // 2) fromTimeSatisfied (bool): indicates if fromTime is satisfied
 // 3) untilTimeSatisfied (bool): indicates if untilTime is satisfied
-// 4) err (error): indicates if an error was encountered during execution
-func processLine(line []byte, leStartRegexp *regexp.Regexp, leTimeFormat string,
-	fromTime time.Time, untilTime time.Time) (bool, bool, bool, error) {
+// 4) leTime (time.Time): the time of logging (zero if timeParser is nil)
+// 5) err (error): indicates if an error was encountered during execution
+// lineOffset is the position of line in the log file. If the time stamp can't
+// be parsed, badTimes (if not nil) determines whether the line is treated as
+// part of the previous log entry (startOfLe is false), as a log entry with the
+// previous log entry's time, or as a log entry that should be skipped, in which
+// case errSkipLogEntry is returned. If the search doesn't need the time of
+// logging (see timeParser.required), a time stamp that can't be parsed is
+// instead left as the zero time, and all time constraints are satisfied.
+func processLine(line []byte, lineOffset int64, leStartRegexp *regexp.Regexp,
+	timeParser *timeParser, fromTime time.Time, untilTime time.Time,
+	badTimes *badTimeState) (bool, bool, bool, time.Time, error) {
 	// find matches in "line" with leStartRegexp
-	matches := leStartRegexp.FindSubmatch(line)
+	matches := leStartRegexp.FindSubmatchIndex(line)
 
 	if matches == nil {
 		// line does not resemble the first line of a log entry, so return
-		return false, false, false, nil
+		return false, false, false, time.Time{}, nil
 	} // beyond this if statement, it is assumed that the line is the first line of
 	// a log entry because leStartRegexp has matched
 
-	// if there're no user-specified time constraints, return (indicating all time
-	// constraints are satisfied)
-	if fromTime.IsZero() && untilTime.IsZero() {
-		return true, true, true, nil
+	// if there's no time format (which means there're no user-specified time
+	// constraints either), return (indicating all time constraints are satisfied)
+	if timeParser == nil {
+		return true, true, true, time.Time{}, nil
 	}
 
-	// check that there was one (and only one) capturing group defined in leStartRegexp
-	if len(matches) == 0 { // sanity check
-		return true, false, false, errors.New(`matches is empty`)
-	} else if len(matches) < 2 {
-		return true, false, false, errors.New(LeStartPatternBadlyFormed +
-			", a capturing group is needed to identify log time")
-	} else if len(matches) > 2 {
-		return true, false, false, errors.New(LeStartPatternBadlyFormed +
-			", there should only be one capturing group to identify log time")
-	}
-
-	// retrieve capturing group 1 (i.e. the log entry's time stamp)
-	leTimeB := matches[1]
-
 	// create Time struct that represents log entry's time of logging
-	leTime, err := time.Parse(leTimeFormat, string(leTimeB))
-	if leTime.IsZero() { // leTimeFormat doesn't match
-		// if time constraints exist, it must be possible to infer the log entry's
-		// time of logging, so an error must be returned
-		return true, false, false, errors.New(LeTimeFormatMismatch)
+	leTime, err := timeParser.parse(line, matches, lineOffset)
+	if err != nil && !timeParser.required {
+		// the time of logging is only passed on in LogEntry.Time
+		return true, true, true, time.Time{}, nil
 	}
-	if err != nil { // sanity check
-		return true, false, false, err
+	if err != nil {
+		if badTimes == nil || badTimes.policy == BadTimeAbort ||
+			!errors.Is(err, ErrLeTimeFormatMismatch) {
+			return true, false, false, time.Time{}, err
+		}
+		badTimes.badTimes++
+		switch {
+		case badTimes.policy == BadTimeMerge:
+			return false, false, false, time.Time{}, nil
+		case badTimes.policy == BadTimeInherit && !badTimes.prevTime.IsZero():
+			leTime = badTimes.prevTime
+		default:
+			badTimes.skippedEntries++
+			return true, false, false, time.Time{}, errSkipLogEntry
+		}
+	}
+	if badTimes != nil {
+		badTimes.prevTime = leTime
 	}
 
 	// check leTime against time constraints
@@ -198,7 +433,7 @@ func processLine(line []byte, leStartRegexp *regexp.Regexp, leTimeFormat string,
 		untilTimeSatisfied = untilTime.After(leTime)
 	}
 
-	return true, fromTimeSatisfied, untilTimeSatisfied, nil
+	return true, fromTimeSatisfied, untilTimeSatisfied, leTime, nil
 }
 
 // findLogEntries starts by analysing buf for newline characters. After finding
@@ -209,24 +444,33 @@ func processLine(line []byte, leStartRegexp *regexp.Regexp, leTimeFormat string,
 // up until the last position at which leStartRegexp matched, to processLogEntry.
 // If a line matches leStartRegexp but fails to satisfy untilTime, it'll continue
 // to traverse, but when a line matches leStartRegexp and fails to satisfy fromTime,
-// findLogEntries will stop traversal and return abort status indicator as true.
+// findLogEntries will stop traversal and return abort status indicator as true
+// (as it also does when handler returns Stop).
 // Upon calling findLogEntries, it is assumed that the last position at which
 // leStartRegexp matched is len(buf). scanToPos and lastNlPos parameters exist
 // as a means for code that calls findLogEntries iteratively to tell findLogEntries
 // where it last "finished off"; scanToPos indicates the position in buf from which
 // findLogEntries has already analysed the bytes in a previous call. lastNlPos
 // was the position past this point in which the last newline was found and hence
-// from where line traversal can continue. The following values are returned:
+// from where line traversal can continue. timeParser infers log entries' time
+// of logging (see processLine), opts determines which byte sequences
+// are newlines, and the maximum length of a log entry, and badTimes keeps track
+// of log entries whose time stamps can't be parsed (see processLine). The
+// following values are returned:
 // 1) lastLePos (int): indicates the first position in the buf at which the last
 //    log entry was discovered
 // 2) lastNlPos (int): indicates the first position in the buf at which the last
 //		newline was found - this helps to save re-analysing bytes which currently
 //		exist between buf[0:lastLePos]
-// 3) abort (bool): indicates if fromTime is no longer satisfied
-// 4) err (error)
-func findLogEntries(buf []byte, bOffset int64, scanToPos int, lastNlPos int,
-	leStartRegexp *regexp.Regexp, leTimeFormat string, fromTime time.Time, untilTime time.Time,
-	regexps []*regexp.Regexp, outputHandler OutputHandler) (int, int, bool, error) {
+// 3) abort (bool): indicates if fromTime is no longer satisfied (i.e. a log
+// entry was logged before fromTime minus tolerance), or handler returned Stop
+// 4) lastLeSatisfied (bool): indicates if the last log entry discovered
+// satisfied the time constraints (only meaningful if one was discovered)
+// 5) err (error): ctx.Err() if ctx is done before all lines have been traversed
+func findLogEntries(ctx context.Context, buf []byte, bOffset int64, scanToPos int, lastNlPos int,
+	leStartRegexp *regexp.Regexp, timeParser *timeParser, fromTime time.Time, untilTime time.Time,
+	tolerance time.Duration, regexps []*regexp.Regexp, handler EntryHandler, opts *Options,
+	badTimes *badTimeState) (int, int, bool, bool, error) {
 
 	/* --- initialise variable for tracking analysis of buf --- */
 	// nlPosStack stacks variables of the form [2]int where [0] denotes the position
@@ -236,9 +480,13 @@ func findLogEntries(buf []byte, bOffset int64, scanToPos int, lastNlPos int,
 
 	bufLen := len(buf)
 
+	// whether \r\n is treated as a newline (as well as \n)
+	crlf := opts.Newlines.crlf()
+
 	// it is assumed last log entry was found after the contents of this buffer
 	// (relative to buf's offset in the log file)
 	lastLePos := bufLen
+	lastLeSatisfied := false
 	bufIndex := 1
 
 	// if bOffset == 0, it means this is the last buf load of bytes in the file,
@@ -249,7 +497,7 @@ func findLogEntries(buf []byte, bOffset int64, scanToPos int, lastNlPos int,
 			// newline character, but no other characters, before the first log entry
 			if buf[0] == '\n' { // first character in file is \n
 				nlPosStack.Push([2]int{0, 1})
-			} else if bufLen >= 2 && buf[0] == '\r' && buf[1] == '\n' {
+			} else if crlf && bufLen >= 2 && buf[0] == '\r' && buf[1] == '\n' {
 				// first character in file is \r\n
 				nlPosStack.Push([2]int{0, 2})
 				// required so we don't stack this newline char again in the following loop
@@ -258,7 +506,7 @@ func findLogEntries(buf []byte, bOffset int64, scanToPos int, lastNlPos int,
 				nlPosStack.Push([2]int{0, 0})
 			}
 		} else { // bOffset < 0 ; return error
-			return lastLePos, lastNlPos, false, errors.New(BufOffsetLessThanZero)
+			return lastLePos, lastNlPos, false, false, ErrBufOffsetLessThanZero
 		}
 	}
 
@@ -272,7 +520,7 @@ func findLogEntries(buf []byte, bOffset int64, scanToPos int, lastNlPos int,
 
 	// find index positions of newlines in buf (and their corresponding byte sizes)
 	for bufIndex <= scanToPos {
-		if buf[bufIndex-1] == '\r' && buf[bufIndex] == '\n' {
+		if crlf && buf[bufIndex-1] == '\r' && buf[bufIndex] == '\n' {
 			nlPosStack.Push([2]int{bufIndex - 1, 2})
 		} else if buf[bufIndex] == '\n' {
 			nlPosStack.Push([2]int{bufIndex, 1})
@@ -282,8 +530,16 @@ func findLogEntries(buf []byte, bOffset int64, scanToPos int, lastNlPos int,
 	}
 
 	// iterative through all newlines in reverse
+	done := ctx.Done()
 	nlData := nlPosStack.Pop()
 	for nlData != nil {
+		// stop traversing if ctx has been canceled or its deadline has passed
+		select {
+		case <-done:
+			return lastLePos, lastNlPos, false, lastLeSatisfied, ctx.Err()
+		default:
+		}
+
 		// retrieve newline info from nlData
 		nlInfo := nlData.([2]int)
 		nlPos := nlInfo[0]
@@ -291,81 +547,115 @@ func findLogEntries(buf []byte, bOffset int64, scanToPos int, lastNlPos int,
 
 		// determine if the bytes between nlPos and lastNlPos is the first line of a
 		// log entry and if so, if it satisfies time constraints
-		startOfLe, fromTimeSatisfied, untilTimeSatisfied, err := processLine(
-			buf[nlPos+nlSize:lastNlPos], leStartRegexp, leTimeFormat, fromTime, untilTime,
+		startOfLe, fromTimeSatisfied, untilTimeSatisfied, leTime, err := processLine(
+			buf[nlPos+nlSize:lastNlPos], bOffset+int64(nlPos+nlSize), leStartRegexp,
+			timeParser, fromTime, untilTime, badTimes,
 		)
+		if err == errSkipLogEntry {
+			// the log entry isn't passed on, but it still ends the log entry before it
+			fromTimeSatisfied, untilTimeSatisfied, err = true, false, nil
+		}
 		if err != nil {
 			if startOfLe {
 				lastLePos = nlPos
 			}
-			return lastLePos, nlPos, false, err
+			return lastLePos, nlPos, false, false, err
 		}
 
 		if startOfLe { // leStartRegexp matched bytes between nlPos and lastNlPos
-			if !fromTimeSatisfied {
-				// if fromTime failed, no further log entries in the log file can match,
-				// so return abort status as true
-				return nlPos, nlPos, true, nil
+			if !fromTimeSatisfied && leTime.Before(fromTime.Add(-tolerance)) {
+				// if fromTime failed (by more than tolerance), no further log entries in
+				// the log file can match, so return abort status as true
+				return nlPos, nlPos, true, false, nil
 			}
-			if untilTimeSatisfied {
-				processLogEntry(buf[nlPos+nlSize:lastLePos], regexps, outputHandler)
+			if fromTimeSatisfied && untilTimeSatisfied {
+				control, err := processLogEntry(buf[nlPos+nlSize:lastLePos], leTime,
+					bOffset+int64(nlPos+nlSize), opts.MaxEntryLen, leStartRegexp, regexps, handler)
+				if err != nil {
+					return nlPos, nlPos, false, true, err
+				}
+				if control == Stop {
+					// the handler has asked for the search to end, so return abort status
+					// as true
+					return nlPos, nlPos, true, true, nil
+				}
 			}
 			// update position at which last log entry has been found
 			lastLePos = nlPos
+			lastLeSatisfied = fromTimeSatisfied && untilTimeSatisfied
 		}
 
 		lastNlPos = nlPos
 		nlData = nlPosStack.Pop()
 	}
 
-	return lastLePos, lastNlPos, false, nil
+	return lastLePos, lastNlPos, false, lastLeSatisfied, nil
 }
 
-// ReverseSearch searches the log file specified by filePath for matching
-// log entries. "Matching log entries" are those that match all regular expressions
-// contained in searchCriteria.Regexps, while satisfying any specified time constraints.
-// In addition, the first log entry in the reverse traversal of the log file that fails
-// the searchCriteria.FromTime constraint will trigger the abort mechanism, which will
-// end the search process. Matching log entries are passed to outputHandler as
-// they're found. There are two return variables:
-//
-// 1) exitStatus (int): -1 indicates an error was found, 0 indicates normal
-// execution without issues, 1 indicates file is empty (not considered an error)
-//
-// 2) err (error)
-//
-// Please refer to examples/main.go for examples of function usage.
-func ReverseSearch(filePath string, searchCriteria *SearchCriteria,
-	outputHandler OutputHandler) (int, error) {
+// outputEntryHandler adapts outputHandler, which cannot end the search early,
+// to an EntryHandler. If outputHandler is nil, defaultOutputHandler is used.
+func outputEntryHandler(outputHandler OutputHandler) EntryHandler {
+	if outputHandler == nil {
+		outputHandler = defaultOutputHandler
+	}
+	return func(logEntry LogEntry) (Control, error) {
+		outputHandler(logEntry.Bytes)
+		return Continue, nil
+	}
+}
+
+// controlEntryHandler adapts controlHandler to an EntryHandler. If
+// controlHandler is nil, defaultOutputHandler is used.
+func controlEntryHandler(controlHandler ControlHandler) EntryHandler {
+	if controlHandler == nil {
+		return outputEntryHandler(nil)
+	}
+	return func(logEntry LogEntry) (Control, error) {
+		return controlHandler(logEntry.Bytes)
+	}
+}
 
-	// validate parameters
-	if (!searchCriteria.FromTime.IsZero() || !searchCriteria.UntilTime.IsZero()) &&
-		searchCriteria.LeTimeFormat == "" {
-		return -1, errors.New(NoLeTimeFormat)
+// defaultOutputHandler is used when no output handler is passed to a search
+// function; it prints matching log entries to STDOUT
+func defaultOutputHandler(logEntry []byte) { fmt.Println(string(logEntry)) }
+
+// validateSearchCriteria checks that searchCriteria contains everything required
+// to carry out a search, and that its fields don't contradict one another
+func validateSearchCriteria(searchCriteria *SearchCriteria) error {
+	if !searchCriteria.FromTime.IsZero() || !searchCriteria.UntilTime.IsZero() ||
+		searchCriteria.LeTimeTemplate != "" {
+		// every start pattern needs a layout for its time stamps
+		for _, formats := range patternTimeFormats(searchCriteria) {
+			if len(formats) == 0 {
+				return ErrNoLeTimeFormat
+			}
+		}
+	}
+	if searchCriteria.LeStartPattern != "" && len(searchCriteria.LeStartPatterns) > 0 {
+		return ErrMultipleLeStartPatterns
 	}
-	if searchCriteria.LeStartPattern == "" {
-		return -1, errors.New(NoLeStartPattern)
+	if searchCriteria.LeStartPattern == "" && len(searchCriteria.LeStartPatterns) == 0 {
+		return ErrNoLeStartPattern
 	}
 	if (!searchCriteria.FromTime.IsZero() && !searchCriteria.UntilTime.IsZero()) &&
 		(searchCriteria.FromTime.After(searchCriteria.UntilTime) ||
 			searchCriteria.UntilTime.Equal(searchCriteria.FromTime)) {
-		return -1, errors.New(FromTimeAfterUntilTime)
+		return ErrFromTimeAfterUntilTime
 	}
-
-	// open file
-	file, err := os.Open(filePath)
-	if err != nil {
-		return -1, err
+	if searchCriteria.Tolerance < 0 {
+		return ErrNegativeTolerance
 	}
-	defer file.Close()
-
-	// get file size
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return -1, err
-	}
-	fileSize := fileInfo.Size()
+	return checkTimeZones(searchCriteria)
+}
 
+// compileSearchCriteria compiles searchCriteria.LeStartPattern (or the
+// combination of LeStartPatterns, see combineStartPatterns) and each of the
+// regular expressions in searchCriteria.Regexps. The return values are:
+// 1) leStartRegexp (*regexp.Regexp): the compiled LeStartPattern
+// 2) regexps ([]*regexp.Regexp): the compiled Regexps (nil if there are none)
+// 3) err (error)
+func compileSearchCriteria(searchCriteria *SearchCriteria) (*regexp.Regexp,
+	[]*regexp.Regexp, error) {
 	// declare and initialise slice of compiled regexps
 	var regexps []*regexp.Regexp
 	if searchCriteria.Regexps != nil {
@@ -377,72 +667,123 @@ func ReverseSearch(filePath string, searchCriteria *SearchCriteria,
 			regexps[i], err = regexp.Compile(regStr)
 			if err != nil {
 				if strings.Contains(err.Error(), `error parsing regexp`) {
-					return -1, errors.New(BadRegexps)
+					return nil, nil, ErrBadRegexps
 				}
-				return -1, err
+				return nil, nil, err
 			}
 		}
 	}
 
-	// compile searchCriteria.LeStartPattern
-	leStartRegexp, err := regexp.Compile(searchCriteria.LeStartPattern)
+	// compile searchCriteria.LeStartPattern, or the combination of
+	// searchCriteria.LeStartPatterns
+	leStartPattern := searchCriteria.LeStartPattern
+	var err error
+	if len(searchCriteria.LeStartPatterns) > 0 {
+		leStartPattern, err = combineStartPatterns(searchCriteria.LeStartPatterns)
+	}
+	var leStartRegexp *regexp.Regexp
+	if err == nil {
+		leStartRegexp, err = regexp.Compile(leStartPattern)
+	}
 	if err != nil {
 		if strings.Contains(err.Error(), `error parsing regexp`) {
-			return -1, errors.New(BadLeStartPattern)
+			return nil, nil, ErrBadLeStartPattern
 		}
-		return -1, err
+		return nil, nil, err
 	}
 
-	// if user did not specify an output handler, set it to fmt.Println
-	var outHandler func([]byte)
-	if outputHandler == nil {
-		outHandler = func(logEntry []byte) { fmt.Println(string(logEntry)) }
-	} else {
-		outHandler = outputHandler
+	return leStartRegexp, regexps, nil
+}
+
+// readAt reads len(p) bytes from file at off into p. An error is returned if
+// fewer bytes are read (io.ErrUnexpectedEOF if file didn't give a reason), as
+// the bytes left in p would otherwise be searched as though they were in the
+// file. io.EOF is only accepted along with a full read.
+func readAt(file io.ReaderAt, p []byte, off int64) error {
+	n, err := file.ReadAt(p, off)
+	switch {
+	case n == len(p) && (err == nil || err == io.EOF):
+		return nil
+	case err == nil || err == io.EOF:
+		return io.ErrUnexpectedEOF
 	}
+	return err
+}
 
-	// required because the last char in a log file is usually a newline - we remove
-	// it because otherwise it would be considered as part of the last log entry
-	// in the file which would be inconsistent & incorrect
+// trimTrailingNewline returns the size of the file once a single trailing
+// newline (\n, or \r\n if newlines allows it) has been discounted from
+// fileSize. The last char in a log file is usually a newline, and if it were not
+// discounted it would be considered part of the last log entry in the file.
+func trimTrailingNewline(file io.ReaderAt, fileSize int64,
+	newlines NewlinePolicy) (int64, error) {
 	if fileSize >= 2 {
 		b := make([]byte, 2)
-		_, err = file.ReadAt(b, fileSize-2)
-		if err != nil {
-			return -1, err
+		if err := readAt(file, b, fileSize-2); err != nil {
+			return fileSize, err
 		}
-		if b[0] == '\r' && b[1] == '\n' {
+		if newlines.crlf() && b[0] == '\r' && b[1] == '\n' {
 			fileSize = fileSize - 2
 		} else if b[1] == '\n' {
 			fileSize = fileSize - 1
 		}
 	} else if fileSize == 1 {
 		b := make([]byte, 1)
-		_, err = file.ReadAt(b, 0)
-		if err != nil {
-			return -1, err
+		if err := readAt(file, b, 0); err != nil {
+			return fileSize, err
 		}
 		if b[0] == '\n' {
 			fileSize = 0
 		}
 	}
+	return fileSize, nil
+}
+
+// reverseSearch traverses the first fileSize bytes of file in reverse, passing
+// log entries that match the search criteria to handler as they're found.
+// It returns the same values as ReverseSearch, along with the abort status
+// indicator (i.e. whether a log entry failed searchCriteria.FromTime, or handler
+// returned Stop) as the second return value. ctx is checked between buf loads
+// and between lines; if it is done, a *CanceledError is returned. opts must
+// already have been resolved (see resolveOptions).
+func reverseSearch(ctx context.Context, file io.ReaderAt, fileSize int64, leStartRegexp *regexp.Regexp,
+	regexps []*regexp.Regexp, searchCriteria *SearchCriteria,
+	handler EntryHandler, opts *Options) (int, bool, error) {
+
+	// required because the last char in a log file is usually a newline - we remove
+	// it because otherwise it would be considered as part of the last log entry
+	// in the file which would be inconsistent & incorrect
+	fileSize, err := trimTrailingNewline(file, fileSize, opts.Newlines)
+	if err != nil {
+		return -1, false, err
+	}
+
+	// keep track of log entries whose time stamps can't be parsed
+	badTimes := newBadTimeState(opts)
+	defer badTimes.report(opts.Stats)
+	timeParser := newTimeParser(leStartRegexp, searchCriteria, yearReference(file, opts), true)
 
 	// check file is not empty
 	if fileSize < 1 {
 		if fileSize < 0 { // sanity check
-			return -1, errors.New("file size is less than 0")
+			return -1, false, errors.New("file size is less than 0")
+		}
+		if opts.EmptyFileIsError {
+			return -1, false, ErrFileIsEmpty
 		}
-		return 1, nil
+		return 1, false, nil
 	}
 
 	// initialise buf related variables
 	bufOffset := fileSize
 	var bufLen int
-	if int64(StartBufLen) > fileSize {
+	if int64(opts.StartBufLen) > fileSize {
 		bufLen = int(fileSize)
 	} else {
-		bufLen = StartBufLen
+		bufLen = opts.StartBufLen
 	}
-	buf := make([]byte, bufLen)
+	pooledBuf := opts.getBuf(bufLen)
+	defer opts.putBuf(pooledBuf)
+	buf := pooledBuf
 
 	// denotes buf position of the start of the last log entry found in buf
 	var lastLePos int
@@ -460,12 +801,23 @@ func ReverseSearch(filePath string, searchCriteria *SearchCriteria,
 	var scanToPos int
 	var lastNlPos int
 
-	// signal for when a found log entry fails searchCriteria.fromTime constraint
+	// signal for when a found log entry fails searchCriteria.fromTime constraint,
+	// or handler asks for the search to end
 	abort := false
 
+	// whether the oldest log entry found so far satisfies the time constraints;
+	// the preamble is only passed on if the first log entry in the file does
+	var firstLeSatisfied bool
+
 	// traverse file backwards, taking a buf's load of bytes at a time from bufOffset,
 	// stopping when bufOFfset > 0 or when fromTime can no longer be satisfied
 	for bufOffset > 0 && !abort {
+		// stop if ctx has been canceled or its deadline has passed; everything from
+		// the start of the last log entry found onwards has been searched
+		if err := ctx.Err(); err != nil {
+			return -1, false, &CanceledError{Offset: bufOffset + int64(lastLePos), Err: err}
+		}
+
 		if lastLePos < bufLen {
 			// at least 1 log entry was detected in buf during the call to findLogEntries
 			// (or it's the first iteration)
@@ -497,15 +849,17 @@ func ReverseSearch(filePath string, searchCriteria *SearchCriteria,
 
 			// reads bytes from bufOffset up to just before the first position of
 			// the bytes we should shifted
-			file.ReadAt(buf[:bufLen-lastLePos], bufOffset)
+			if err := readAt(file, buf[:bufLen-lastLePos], bufOffset); err != nil {
+				return -1, false, err
+			}
 		} else if lastLePos == bufLen {
 			// no log entries were detected in buf which suggests buf length may be too
 			// small
 
 			// increase length of buffer & update related variables
-			nAdded, err := increaseBufLen(&buf)
+			nAdded, err := increaseBufLen(&buf, opts.MaxBufLen)
 			if err != nil {
-				return -1, err
+				return -1, false, err
 			}
 			bufLen += nAdded
 			bufOffset -= int64(nAdded)
@@ -526,19 +880,29 @@ func ReverseSearch(filePath string, searchCriteria *SearchCriteria,
 
 			// reads bytes from bufOffset up to just before the first position of
 			// the bytes that were shifted during the increaseBufLen function call
-			file.ReadAt(buf[:nAdded], bufOffset)
+			if err := readAt(file, buf[:nAdded], bufOffset); err != nil {
+				return -1, false, err
+			}
 		} else { // sanity check
-			return -1, errors.New("lastLePos is more than bufLen")
+			return -1, false, errors.New("lastLePos is more than bufLen")
 		}
 
 		// find log entries in buf, and pass the ones that match the specified regexps
-		// while satisfying the time constraints to the outputHandler. abort will be
-		// returned as true if any found log entries fail searchCriteria.FromTime
-		lastLePos, lastNlPos, abort, err = findLogEntries(buf, bufOffset, scanToPos,
-			lastNlPos, leStartRegexp, searchCriteria.LeTimeFormat, searchCriteria.FromTime,
-			searchCriteria.UntilTime, regexps, outHandler)
+		// while satisfying the time constraints to handler. abort will be returned
+		// as true if any found log entries fail searchCriteria.FromTime, or if
+		// handler returns Stop
+		var lastLeSatisfied bool
+		lastLePos, lastNlPos, abort, lastLeSatisfied, err = findLogEntries(ctx, buf, bufOffset,
+			scanToPos, lastNlPos, leStartRegexp, timeParser, searchCriteria.FromTime,
+			searchCriteria.UntilTime, searchCriteria.Tolerance, regexps, handler, opts, badTimes)
 		if err != nil {
-			return -1, err
+			if ctxErr := ctx.Err(); ctxErr != nil && err == ctxErr {
+				return -1, false, &CanceledError{Offset: bufOffset + int64(lastLePos), Err: err}
+			}
+			return -1, false, err
+		}
+		if lastLePos < len(buf) {
+			firstLeSatisfied = lastLeSatisfied
 		}
 	}
 
@@ -546,11 +910,118 @@ func ReverseSearch(filePath string, searchCriteria *SearchCriteria,
 	// stopped beyond a certain point
 	if bufOffset == 0 && lastLePos != 0 && !abort {
 		if int64(lastLePos) == fileSize {
-			return -1, errors.New(NoLogEntriesInFile)
+			return -1, false, ErrNoLogEntriesInFile
+		}
+		switch opts.Preamble {
+		case PreambleError:
+			return -1, false, &NoMoreEntriesError{Offset: bufOffset + int64(lastLePos)}
+		case PreambleEntry:
+			// the preamble is the oldest "log entry" in the file, so it's found last
+			// (unless the first log entry fails the time constraints, in which case
+			// the preamble is considered to fail them too)
+			if !firstLeSatisfied {
+				break
+			}
+			control, err := processPreamble(buf[:lastLePos], opts.MaxEntryLen, regexps,
+				handler)
+			if err != nil {
+				return -1, false, err
+			}
+			abort = control == Stop
 		}
-		return -1, errors.New(NoMoreLogEntries + `, last log entry found at ` +
-			strconv.FormatInt((bufOffset+int64(lastLePos)), 10))
 	}
 
-	return 0, nil
+	return 0, abort, nil
+}
+
+// ReverseSearchReaderAtContext is the same as ReverseSearchReaderAt, except that
+// the search stops once ctx is done (see ReverseSearchContext).
+func ReverseSearchReaderAtContext(ctx context.Context, r io.ReaderAt, size int64,
+	searchCriteria *SearchCriteria, outputHandler OutputHandler) (int, error) {
+	searcher, err := NewSearcher(searchCriteria, nil)
+	if err != nil {
+		return -1, err
+	}
+	return searcher.ReverseSearchReaderAt(ctx, r, size, outputEntryHandler(outputHandler))
+}
+
+// ReverseSearchReaderAt is the same as ReverseSearch, except that the log file
+// is read from r, which holds size bytes, instead of being opened from a file
+// path. This allows in-memory buffers, fs.FS files and other storage
+// abstractions to be searched. Note that r is read from directly, so it must
+// not be compressed. Return values are the same as those of ReverseSearch.
+func ReverseSearchReaderAt(r io.ReaderAt, size int64, searchCriteria *SearchCriteria,
+	outputHandler OutputHandler) (int, error) {
+	return ReverseSearchReaderAtContext(context.Background(), r, size, searchCriteria,
+		outputHandler)
+}
+
+// ReverseSearchControl is the same as ReverseSearchContext, except that
+// matching log entries are passed to a ControlHandler, which can end the search
+// early; e.g. once the 50 most recent errors have been found. When handler
+// returns Stop, traversal halts immediately and the exit status is 0, as though
+// the search had completed normally. When handler returns an error, traversal
+// halts immediately and that error is returned with an exit status of -1. If
+// handler is nil, matching log entries are printed to STDOUT.
+func ReverseSearchControl(ctx context.Context, filePath string,
+	searchCriteria *SearchCriteria, handler ControlHandler) (int, error) {
+	return ReverseSearchLogEntries(ctx, filePath, searchCriteria, controlEntryHandler(handler))
+}
+
+// ReverseSearchLogEntries is the same as ReverseSearchControl, except that
+// matching log entries are passed to an EntryHandler as LogEntry values, which
+// hold the log entry's parsed time of logging, byte offset, length and line
+// count as well as its bytes. This saves handlers from having to parse time
+// stamps again, and allows them to link back to the position of log entries in
+// the log file. If handler is nil, matching log entries are printed to STDOUT.
+func ReverseSearchLogEntries(ctx context.Context, filePath string,
+	searchCriteria *SearchCriteria, handler EntryHandler) (int, error) {
+	return ReverseSearchWithOptions(ctx, filePath, searchCriteria, nil, handler)
+}
+
+// ReverseSearchWithOptions is the same as ReverseSearchLogEntries, except that
+// the search uses the settings in opts (e.g. buffer lengths and newline policy)
+// rather than the defaults. Since the settings apply to this search only,
+// concurrent searches can use different settings without racing on the
+// package level variables. A nil opts means the defaults are used.
+func ReverseSearchWithOptions(ctx context.Context, filePath string,
+	searchCriteria *SearchCriteria, opts *Options, handler EntryHandler) (int, error) {
+	searcher, err := NewSearcher(searchCriteria, opts)
+	if err != nil {
+		return -1, err
+	}
+	return searcher.ReverseSearch(ctx, filePath, handler)
+}
+
+// ReverseSearchContext is the same as ReverseSearch, except that ctx is checked
+// between buf loads and between the lines that are traversed; once it is
+// canceled, or its deadline passes, the search stops and a *CanceledError
+// holding the byte offset reached so far is returned (with an exit status of
+// -1). This allows callers such as HTTP handlers to bound the duration of a
+// search of a large log file.
+func ReverseSearchContext(ctx context.Context, filePath string,
+	searchCriteria *SearchCriteria, outputHandler OutputHandler) (int, error) {
+	return ReverseSearchLogEntries(ctx, filePath, searchCriteria,
+		outputEntryHandler(outputHandler))
+}
+
+// ReverseSearch searches the log file specified by filePath for matching
+// log entries. "Matching log entries" are those that match all regular expressions
+// contained in searchCriteria.Regexps, while satisfying any specified time constraints.
+// In addition, the first log entry in the reverse traversal of the log file that fails
+// the searchCriteria.FromTime constraint will trigger the abort mechanism, which will
+// end the search process. Matching log entries are passed to outputHandler as
+// they're found. A log file compressed with gzip, bzip2 or zstd is detected by its
+// magic number and decompressed into a temporary file (see TempDir) before being
+// searched. There are two return variables:
+//
+// 1) exitStatus (int): -1 indicates an error was found, 0 indicates normal
+// execution without issues, 1 indicates file is empty (not considered an error)
+//
+// 2) err (error)
+//
+// Please refer to examples/main.go for examples of function usage.
+func ReverseSearch(filePath string, searchCriteria *SearchCriteria,
+	outputHandler OutputHandler) (int, error) {
+	return ReverseSearchContext(context.Background(), filePath, searchCriteria, outputHandler)
 }
@@ -5,7 +5,13 @@ package reversesearch_test
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -666,3 +672,388 @@ func TestRedPathsReverseSearchFile(t *testing.T) {
 		})
 	}
 }
+
+// Green path testing of ReverseSearchReaderAt function, with the log files held
+// in memory rather than in testdata/test_logs
+func TestGreenPathsReverseSearchReaderAt(t *testing.T) {
+	StartBufLen = 256
+
+	line1 := "<Jun 15, 2010 2:01:20 AM IST> <Error> keyword1"
+	line2 := "<Jun 16, 2010 6:02:02 AM IST> <Warning>"
+	line3 := "continuation of keyword1"
+	line4 := "<Jun 17, 2010 11:02:52 PM IST> <Error> keyword2"
+
+	// define tests (which will be iterated over further down)
+	var tests = []struct {
+		name               string         // test name (also description summary)
+		file               string         // contents of the log file to be searched
+		searchCriteria     SearchCriteria // third parameter of ReverseSearchReaderAt
+		expectedExitStatus int            // i.e. the first return val of ReverseSearchReaderAt
+		expectedOutput     []string       // log entries that should be matched
+	}{
+		{
+			name: "test 1: unix newlines",
+			file: line1 + "\n" + line2 + "\n" + line3 + "\n" + line4 + "\n",
+			searchCriteria: SearchCriteria{
+				Regexps:        []string{`keyword1`},
+				LeStartPattern: odlStartPattern,
+			},
+			expectedExitStatus: 0,
+			expectedOutput:     []string{line2 + "\n" + line3, line1},
+		},
+		{
+			name: "test 2: windows newlines with fromTime constraint",
+			file: line1 + "\r\n" + line2 + "\r\n" + line3 + "\r\n" + line4 + "\r\n",
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(odlTimeFormat, `Jun 16, 2010 12:00:00 AM IST`),
+				LeStartPattern: odlStartPattern,
+				LeTimeFormat:   odlTimeFormat,
+			},
+			expectedExitStatus: 0,
+			expectedOutput:     []string{line4, line2 + "\r\n" + line3},
+		},
+		{
+			name:               "test 3: empty file",
+			file:               "",
+			searchCriteria:     SearchCriteria{LeStartPattern: odlStartPattern},
+			expectedExitStatus: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var actual []string
+			exitStatus, err := ReverseSearchReaderAt(strings.NewReader(test.file),
+				int64(len(test.file)), &test.searchCriteria,
+				func(logEntry []byte) { actual = append(actual, string(logEntry)) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(actual, "\n---\n") != strings.Join(test.expectedOutput, "\n---\n") {
+				t.Errorf("GOT:\n%s\nWANT:\n%s", strings.Join(actual, "\n---\n"),
+					strings.Join(test.expectedOutput, "\n---\n"))
+			}
+			if exitStatus != test.expectedExitStatus {
+				t.Errorf("Got exit status: %d, want exit status: %d", exitStatus,
+					test.expectedExitStatus)
+			}
+		})
+	}
+}
+
+// failingReaderAt is an io.ReaderAt whose reads fail once it has been read from
+// okReads times, or are cut short if short is set
+type failingReaderAt struct {
+	r       io.ReaderAt
+	okReads int
+	short   bool
+}
+
+// errReadFailed is the error returned by a failingReaderAt
+var errReadFailed = errors.New("read failed")
+
+func (f *failingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if f.okReads > 0 {
+		f.okReads--
+		return f.r.ReadAt(p, off)
+	}
+	if f.short {
+		return f.r.ReadAt(p[:len(p)/2], off)
+	}
+	return 0, errReadFailed
+}
+
+// Testing of ReverseSearchReaderAt with an io.ReaderAt whose reads fail part way
+// through the search; the read error must be returned, rather than the stale
+// bytes left in the buffer being searched as though they were log entries
+func TestReverseSearchReaderAtReadErrors(t *testing.T) {
+	StartBufLen = 4096
+	var b strings.Builder
+	for i := 0; b.Len() < 130000; i++ {
+		fmt.Fprintf(&b, "<Jun 17, 2010 2:%02d:%02d AM IST> <Info> entry %d\n", i/60%60, i%60, i)
+	}
+	contents := b.String()
+
+	var tests = []struct {
+		name        string
+		short       bool
+		expectedErr error
+	}{
+		{
+			name:        "test 1: read error",
+			expectedErr: errReadFailed,
+		},
+		{
+			name:        "test 2: short read",
+			short:       true,
+			expectedErr: io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nEntries := 0
+			exitStatus, err := ReverseSearchReaderAt(&failingReaderAt{
+				r:       strings.NewReader(contents),
+				okReads: 2,
+				short:   test.short,
+			}, int64(len(contents)), &SearchCriteria{LeStartPattern: odlStartPattern},
+				func([]byte) { nEntries++ })
+			if !errors.Is(err, test.expectedErr) {
+				t.Errorf("Got error: %v, want error that is: %v", err, test.expectedErr)
+			}
+			if exitStatus != -1 {
+				t.Errorf("Got exit status: %d, want exit status: -1", exitStatus)
+			}
+			if max := 2 * StartBufLen / 40; nEntries > max {
+				t.Errorf("Got %d log entries, want no more than the %d read", nEntries, max)
+			}
+		})
+	}
+}
+
+// Testing of ReverseSearchContext and ReverseSearchReaderAtContext; checks
+// that a canceled search returns a *CanceledError, and that the rest of the
+// log file can be searched by searching up to the offset it holds
+func TestReverseSearchContext(t *testing.T) {
+	StartBufLen = 256
+	searchCriteria := SearchCriteria{LeStartPattern: apacheStartPattern}
+
+	// test 1: ctx is already canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	exitStatus, err := ReverseSearchContext(ctx, accessLog, &searchCriteria,
+		func(logEntry []byte) { t.Error("log entry passed to output handler") })
+	var canceledErr *CanceledError
+	if !errors.As(err, &canceledErr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Got error: %v, want *CanceledError wrapping context.Canceled", err)
+	}
+	if exitStatus != -1 {
+		t.Errorf("Got exit status: %d, want exit status: -1", exitStatus)
+	}
+
+	// test 2: ctx deadline has already passed
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	_, err = ReverseSearchContext(ctx, accessLog, &searchCriteria, func([]byte) {})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Got error: %v, want error wrapping context.DeadlineExceeded", err)
+	}
+
+	// test 3: ctx is canceled part way through the search, after which the rest
+	// of the file is searched up to the offset reached
+	contents, err := ioutil.ReadFile(accessLog)
+	check(err)
+	var expected []string
+	_, err = ReverseSearch(accessLog, &searchCriteria,
+		func(logEntry []byte) { expected = append(expected, string(logEntry)) })
+	check(err)
+
+	var actual []string
+	ctx, cancel = context.WithCancel(context.Background())
+	_, err = ReverseSearchReaderAtContext(ctx, bytes.NewReader(contents), int64(len(contents)),
+		&searchCriteria, func(logEntry []byte) {
+			actual = append(actual, string(logEntry))
+			if len(actual) == 100 {
+				cancel()
+			}
+		})
+	if !errors.As(err, &canceledErr) {
+		t.Fatalf("Got error: %v, want *CanceledError", err)
+	}
+	if len(actual) != 100 {
+		t.Errorf("Got %d log entries before cancelation, want 100", len(actual))
+	}
+
+	_, err = ReverseSearchReaderAt(bytes.NewReader(contents[:canceledErr.Offset]),
+		canceledErr.Offset, &searchCriteria,
+		func(logEntry []byte) { actual = append(actual, string(logEntry)) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Got %d log entries, want %d log entries", len(actual), len(expected))
+	}
+}
+
+// Testing of ReverseSearchControl; checks that the search ends as soon as the
+// handler returns Stop or an error
+func TestReverseSearchControl(t *testing.T) {
+	StartBufLen = 256
+	searchCriteria := SearchCriteria{
+		Regexps:        []string{`/modules/mod_araticlhess1/mod_araticlhess1\.php`},
+		LeStartPattern: apacheStartPattern,
+	}
+
+	var expected []string
+	_, err := ReverseSearch(accessLog, &searchCriteria,
+		func(logEntry []byte) { expected = append(expected, string(logEntry)) })
+	check(err)
+	if len(expected) < 10 {
+		t.Fatalf("Got %d matching log entries in %s, want at least 10", len(expected),
+			accessLog)
+	}
+
+	// test 1: handler stops the search after 5 log entries
+	var actual []string
+	exitStatus, err := ReverseSearchControl(context.Background(), accessLog,
+		&searchCriteria, func(logEntry []byte) (Control, error) {
+			actual = append(actual, string(logEntry))
+			if len(actual) == 5 {
+				return Stop, nil
+			}
+			return Continue, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected[:5], "\n") {
+		t.Errorf("Got %d log entries, want the 5 most recent", len(actual))
+	}
+	if exitStatus != 0 {
+		t.Errorf("Got exit status: %d, want exit status: 0", exitStatus)
+	}
+
+	// test 2: handler returns an error after 3 log entries
+	handlerErr := errors.New("handler error")
+	count := 0
+	exitStatus, err = ReverseSearchControl(context.Background(), accessLog,
+		&searchCriteria, func(logEntry []byte) (Control, error) {
+			count++
+			if count == 3 {
+				return Continue, handlerErr
+			}
+			return Continue, nil
+		})
+	if err != handlerErr {
+		t.Errorf("Got error: %v, want error: %v", err, handlerErr)
+	}
+	if count != 3 {
+		t.Errorf("Got %d calls to handler, want 3", count)
+	}
+	if exitStatus != -1 {
+		t.Errorf("Got exit status: %d, want exit status: -1", exitStatus)
+	}
+}
+
+// Testing of ReverseSearchLogEntries; checks that each LogEntry's fields
+// describe the log entry's position and contents in the log file
+func TestReverseSearchLogEntries(t *testing.T) {
+	StartBufLen = 256
+	searchCriteria := SearchCriteria{
+		LeStartPattern: odlStartPattern,
+		LeTimeFormat:   odlTimeFormat,
+	}
+
+	contents, err := ioutil.ReadFile(odlLog)
+	check(err)
+	var expected []string
+	_, err = ReverseSearch(odlLog, &searchCriteria,
+		func(logEntry []byte) { expected = append(expected, string(logEntry)) })
+	check(err)
+
+	var actual []string
+	var prevTime time.Time
+	exitStatus, err := ReverseSearchLogEntries(context.Background(), odlLog,
+		&searchCriteria, func(logEntry LogEntry) (Control, error) {
+			actual = append(actual, string(logEntry.Bytes))
+
+			if logEntry.Len != len(logEntry.Bytes) {
+				t.Errorf("Got Len: %d, want Len: %d", logEntry.Len, len(logEntry.Bytes))
+			}
+			fileBytes := contents[logEntry.Offset : logEntry.Offset+int64(logEntry.Len)]
+			if !bytes.Equal(fileBytes, logEntry.Bytes) {
+				t.Errorf("Bytes at Offset %d do not match log entry:\n%s", logEntry.Offset,
+					logEntry.Bytes)
+			}
+			if lines := len(strings.Split(string(logEntry.Bytes), "\n")); logEntry.Lines != lines {
+				t.Errorf("Got Lines: %d, want Lines: %d", logEntry.Lines, lines)
+			}
+			if logEntry.Time.IsZero() || (!prevTime.IsZero() && logEntry.Time.After(prevTime)) {
+				t.Errorf("Got Time: %v, want non zero time no later than %v", logEntry.Time,
+					prevTime)
+			}
+			prevTime = logEntry.Time
+			return Continue, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Got %d log entries, want %d log entries", len(actual), len(expected))
+	}
+	if exitStatus != 0 {
+		t.Errorf("Got exit status: %d, want exit status: 0", exitStatus)
+	}
+}
+
+// TestUnconstrainedTimeStamps checks that, without time constraints, time stamps
+// that can't be parsed don't stop the search (even if LeTimeTemplate or
+// Tolerance is set); the log entries are matched with a zero Time, as they were
+// before LogEntry.Time existed
+func TestUnconstrainedTimeStamps(t *testing.T) {
+	StartBufLen = 256
+	logFile := filepath.Join(t.TempDir(), "unconstrained.log")
+	lines := []string{"2024-05-01 12:00:00 first", "2024-05-01 12:00:01 second"}
+	check(ioutil.WriteFile(logFile, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+
+	var tests = []struct {
+		name           string
+		searchCriteria SearchCriteria
+	}{
+		{
+			name: "test 1: layout doesn't match",
+			searchCriteria: SearchCriteria{
+				LeStartPattern: `^(\S+ \S+) `,
+				LeTimeFormat:   "02/Jan/2006",
+			},
+		},
+		{
+			name: "test 2: two unnamed capturing groups",
+			searchCriteria: SearchCriteria{
+				LeStartPattern: `^(\S+) (\S+) `,
+				LeTimeFormat:   "02/Jan/2006",
+			},
+		},
+		{
+			name: "test 3: assembled time stamp doesn't match",
+			searchCriteria: SearchCriteria{
+				LeStartPattern: `^(?P<date>\S+) (?P<time>\S+) `,
+				LeTimeTemplate: `${date} ${time}`,
+				LeTimeFormat:   "02/Jan/2006",
+			},
+		},
+		{
+			name: "test 4: tolerance without time constraints",
+			searchCriteria: SearchCriteria{
+				Tolerance:      time.Minute,
+				LeStartPattern: `^(\S+ \S+) `,
+				LeTimeFormat:   "02/Jan/2006",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var actual []string
+			exitStatus, err := ReverseSearchLogEntries(context.Background(), logFile,
+				&test.searchCriteria, func(logEntry LogEntry) (Control, error) {
+					actual = append(actual, string(logEntry.Bytes))
+					if !logEntry.Time.IsZero() {
+						t.Errorf("Got Time: %v, want zero time", logEntry.Time)
+					}
+					return Continue, nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exitStatus != 0 {
+				t.Errorf("Got exit status: %d, want exit status: 0", exitStatus)
+			}
+			expected := []string{lines[1], lines[0]}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("GOT:\n%q\nWANT:\n%q", actual, expected)
+			}
+		})
+	}
+}
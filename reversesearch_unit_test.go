@@ -6,7 +6,10 @@ package since it is tested thoroughly in the reversesearch_test package with the
 integration testing. */
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -104,9 +107,12 @@ func TestFindLogEntries(t *testing.T) {
 			output = "" // reset output
 
 			// execute test call
-			lastLePos, lastNlPos, abort, err := findLogEntries(test.buf, test.bOffset,
-				len(test.buf)-1, len(test.buf), testLeStartRegexp, testLeTimeFormat,
-				testFromTime, testUntilTime, testRegexps, testOutputHandler)
+			lastLePos, lastNlPos, abort, _, err := findLogEntries(context.Background(), test.buf,
+				test.bOffset, len(test.buf)-1, len(test.buf), testLeStartRegexp,
+				newTimeParser(testLeStartRegexp, &SearchCriteria{LeTimeFormat: testLeTimeFormat},
+					time.Time{}, true),
+				testFromTime, testUntilTime, 0, testRegexps, outputEntryHandler(testOutputHandler),
+				resolveOptions(nil), nil)
 
 			// compare output against expected output
 			if output != test.expectedOutput {
@@ -352,9 +358,12 @@ func TestFindLogEntries(t *testing.T) {
 			}
 
 			// execute call to findLogEntries
-			lastLePos, lastNlPos, abort, err := findLogEntries([]byte(test.buf), test.bOffset,
-				scanToPosParam, lastNlPosParam, testLeStartRegexp, testLeTimeFormat, testFromTime,
-				testUntilTime, testRegexps, testOutputHandler)
+			lastLePos, lastNlPos, abort, _, err := findLogEntries(context.Background(),
+				[]byte(test.buf), test.bOffset, scanToPosParam, lastNlPosParam, testLeStartRegexp,
+				newTimeParser(testLeStartRegexp, &SearchCriteria{LeTimeFormat: testLeTimeFormat},
+					time.Time{}, true), testFromTime,
+				testUntilTime, 0, testRegexps, outputEntryHandler(testOutputHandler), resolveOptions(nil),
+				nil)
 			if err != nil {
 				t.Error(err)
 				return
@@ -396,12 +405,14 @@ func TestProcessLine(t *testing.T) {
 
 	// define tests
 	var tests = []struct {
-		name           string    // test name/summary
-		line           string    // 1st input param
-		leStartPattern string    // 2nd input param
-		leTimeFormat   string    // 3rd input param
-		fromTime       time.Time // 4th input param
-		untilTime      time.Time // 5th input param
+		name           string        // test name/summary
+		line           string        // 1st input param
+		leStartPattern string        // 2nd input param
+		leTimeFormat   string        // 3rd input param
+		leTimeTemplate string        // 3rd input param (with leTimeFormat)
+		fromTime       time.Time     // 4th input param
+		untilTime      time.Time     // 5th input param
+		badTimes       *badTimeState // 6th input param
 
 		// expected return values
 		expectedStartOfLe          bool   // expected value of 1st return value
@@ -530,6 +541,56 @@ func TestProcessLine(t *testing.T) {
 			expectedErr:                LeTimeFormatMismatch,
 		},
 
+		// with a lenient bad time policy, the leTimeFormat mismatch is handled as
+		// the policy dictates rather than being returned
+		{
+			name:                       "test: leTimeFormatMismatch, merged",
+			line:                       testLine,
+			leStartPattern:             odlStartPattern,
+			leTimeFormat:               odlTimeFormat + `unmatch`,
+			fromTime:                   parseTime(odlTimeFormat, `Jun 15, 2010 2:00:00 AM IST`),
+			badTimes:                   &badTimeState{policy: BadTimeMerge},
+			expectedStartOfLe:          false,
+			expectedFromTimeSatisfied:  false,
+			expectedUntilTimeSatisfied: false,
+		},
+		{
+			name:                       "test: leTimeFormatMismatch, skipped",
+			line:                       testLine,
+			leStartPattern:             odlStartPattern,
+			leTimeFormat:               odlTimeFormat + `unmatch`,
+			fromTime:                   parseTime(odlTimeFormat, `Jun 15, 2010 2:00:00 AM IST`),
+			badTimes:                   &badTimeState{policy: BadTimeSkip},
+			expectedStartOfLe:          true,
+			expectedFromTimeSatisfied:  false,
+			expectedUntilTimeSatisfied: false,
+			expectedErr:                errSkipLogEntry.Error(),
+		},
+		{
+			name:           "test: leTimeFormatMismatch, inherits previous time",
+			line:           testLine,
+			leStartPattern: odlStartPattern,
+			leTimeFormat:   odlTimeFormat + `unmatch`,
+			fromTime:       parseTime(odlTimeFormat, `Jun 15, 2010 2:00:00 AM IST`),
+			badTimes: &badTimeState{policy: BadTimeInherit,
+				prevTime: parseTime(odlTimeFormat, `Jun 15, 2010 1:00:00 AM IST`)},
+			expectedStartOfLe:          true,
+			expectedFromTimeSatisfied:  false,
+			expectedUntilTimeSatisfied: true,
+		},
+		{
+			name:                       "test: leTimeFormatMismatch, nothing to inherit",
+			line:                       testLine,
+			leStartPattern:             odlStartPattern,
+			leTimeFormat:               odlTimeFormat + `unmatch`,
+			fromTime:                   parseTime(odlTimeFormat, `Jun 15, 2010 2:00:00 AM IST`),
+			badTimes:                   &badTimeState{policy: BadTimeInherit},
+			expectedStartOfLe:          true,
+			expectedFromTimeSatisfied:  false,
+			expectedUntilTimeSatisfied: false,
+			expectedErr:                errSkipLogEntry.Error(),
+		},
+
 		// if user has specified no capturing group in leStartPattern,
 		// an error should be thrown
 		{
@@ -558,6 +619,61 @@ func TestProcessLine(t *testing.T) {
 			expectedErr:                LeStartPatternBadlyFormed,
 		},
 
+		// if the time stamp is captured by a group named ts, there can be any number
+		// of other capturing groups
+		{
+			name:                       "test: ts named capturing group among others",
+			line:                       testLine,
+			leStartPattern:             `^<(?P<ts>\w{3} \d{2}, \d{4} \d{1,2}:\d{2}:\d{2} (?:AM|PM) \S+)> <(\w+)> <(?P<logger>[^>]+)>`,
+			leTimeFormat:               odlTimeFormat,
+			fromTime:                   parseTime(odlTimeFormat, `Jun 15, 2010 2:00:00 AM IST`),
+			expectedStartOfLe:          true,
+			expectedFromTimeSatisfied:  true,
+			expectedUntilTimeSatisfied: true,
+		},
+
+		// a ts named capturing group that captures something other than the time
+		// stamp is a mismatch
+		{
+			name:                       "test: ts named capturing group doesn't capture time stamp",
+			line:                       testLine,
+			leStartPattern:             `^<(\w{3} \d{2}, \d{4} \d{1,2}:\d{2}:\d{2} (?:AM|PM) \S+)> <(?P<ts>\w+)>`,
+			leTimeFormat:               odlTimeFormat,
+			fromTime:                   parseTime(odlTimeFormat, `Jun 15, 2010 2:00:00 AM IST`),
+			expectedStartOfLe:          true,
+			expectedFromTimeSatisfied:  false,
+			expectedUntilTimeSatisfied: false,
+			expectedErr:                LeTimeFormatMismatch,
+		},
+
+		// the time stamp can be assembled from several capturing groups
+		{
+			name:                       "test: time stamp assembled with leTimeTemplate",
+			line:                       `2024-05-01 | 12:03:44.123 INFO started`,
+			leStartPattern:             `^(?P<date>\d{4}-\d{2}-\d{2}) \| (?P<time>[\d:.]+) `,
+			leTimeFormat:               `2006-01-02 15:04:05.000`,
+			leTimeTemplate:             `${date} ${time}`,
+			fromTime:                   parseTime(`2006-01-02 15:04:05.000`, `2024-05-01 12:03:44.123`),
+			untilTime:                  parseTime(`2006-01-02 15:04:05.000`, `2024-05-01 12:03:44.124`),
+			expectedStartOfLe:          true,
+			expectedFromTimeSatisfied:  true,
+			expectedUntilTimeSatisfied: true,
+		},
+
+		// the assembled time stamp must match leTimeFormat
+		{
+			name:                       "test: time stamp assembled with leTimeTemplate doesn't match",
+			line:                       `2024-05-01 | 12:03:44.123 INFO started`,
+			leStartPattern:             `^(?P<date>\d{4}-\d{2}-\d{2}) \| (?P<time>[\d:.]+) `,
+			leTimeFormat:               `2006-01-02 15:04:05.000`,
+			leTimeTemplate:             `${time} ${date}`,
+			fromTime:                   parseTime(`2006-01-02 15:04:05.000`, `2024-05-01 12:00:00.000`),
+			expectedStartOfLe:          true,
+			expectedFromTimeSatisfied:  false,
+			expectedUntilTimeSatisfied: false,
+			expectedErr:                LeTimeFormatMismatch,
+		},
+
 		// check empty line can be handled OK
 		{
 			name:                       "test: empty line buf",
@@ -585,9 +701,15 @@ func TestProcessLine(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			// invoke processLine with test parameters
-			startOfLe, fromTimeSatisfied, untilTimeSatisfied, err := processLine(
-				[]byte(test.line), compileRegexp(test.leStartPattern),
-				test.leTimeFormat, test.fromTime, test.untilTime,
+			leStartRegexp := compileRegexp(test.leStartPattern)
+			startOfLe, fromTimeSatisfied, untilTimeSatisfied, leTime, err := processLine(
+				[]byte(test.line), 0, leStartRegexp,
+				newTimeParser(leStartRegexp, &SearchCriteria{
+					FromTime:       test.fromTime,
+					UntilTime:      test.untilTime,
+					LeTimeFormat:   test.leTimeFormat,
+					LeTimeTemplate: test.leTimeTemplate,
+				}, time.Time{}, false), test.fromTime, test.untilTime, test.badTimes,
 			)
 
 			// compare err with expectedErr
@@ -623,6 +745,14 @@ func TestProcessLine(t *testing.T) {
 					" Got %t, want %t",
 					untilTimeSatisfied, test.expectedUntilTimeSatisfied)
 			}
+
+			// leTime (4th return value) should only be set when the line's time stamp
+			// has been parsed
+			expectingLeTime := startOfLe && test.leTimeFormat != "" && err == nil
+			if leTime.IsZero() == expectingLeTime {
+				t.Errorf("leTime does not match expectation. Got %v, want zero time: %t",
+					leTime, !expectingLeTime)
+			}
 		})
 	}
 }
@@ -705,7 +835,8 @@ func TestProcessLogEntry(t *testing.T) {
 			matchFound = false
 
 			// call processLogEntry
-			processLogEntry(test.logEntry, compileRegexps(test.regexps), testOutputHandler)
+			processLogEntry(test.logEntry, time.Time{}, 0, 0, nil, compileRegexps(test.regexps),
+				outputEntryHandler(testOutputHandler))
 
 			// compare matchFound with expectingMatch, and check the expected value
 			// (logEntry) is being passed to outputHandler
@@ -718,3 +849,289 @@ func TestProcessLogEntry(t *testing.T) {
 		})
 	}
 }
+
+// test leFields (greenpaths only as there are no red paths)
+func TestLeFields(t *testing.T) {
+	var tests = []struct {
+		name           string            // name/summary of test
+		leStartPattern string            // 1st parameter
+		logEntry       string            // 2nd parameter
+		expectedFields map[string]string // expected return value
+	}{
+		{
+			name:           "test: no named capturing groups",
+			leStartPattern: odlStartPattern,
+			logEntry:       "<Jun 15, 2010 2:02:02 AM IST> <Warning> <oracle.iam>",
+		},
+		{
+			name:           "test: only the ts named capturing group",
+			leStartPattern: `^<(?P<ts>[^>]+)>`,
+			logEntry:       "<Jun 15, 2010 2:02:02 AM IST> <Warning> <oracle.iam>",
+		},
+		{
+			name:           "test: named capturing groups, multi line log entry",
+			leStartPattern: `^<(?P<ts>[^>]+)> <(?P<level>\w+)> <([^>]+)>(?: <(?P<thread>[^>]+)>)?$`,
+			logEntry:       "<Jun 15, 2010 2:02:02 AM IST> <Warning> <oracle.iam>\r\n<stack trace>",
+			expectedFields: map[string]string{"level": "Warning"},
+		},
+		{
+			name:           "test: unnamed time stamp capturing group",
+			leStartPattern: `^<([^>]+)> <(?P<level>\w+)> <(?P<logger>[^>]+)>`,
+			logEntry:       "<Jun 15, 2010 2:02:02 AM IST> <Warning> <oracle.iam>",
+			expectedFields: map[string]string{"level": "Warning", "logger": "oracle.iam"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fields := leFields(regexp.MustCompile(test.leStartPattern), []byte(test.logEntry))
+			if fmt.Sprint(fields) != fmt.Sprint(test.expectedFields) ||
+				(fields == nil) != (test.expectedFields == nil) {
+				t.Errorf("Got fields: %v, want fields: %v", fields, test.expectedFields)
+			}
+		})
+	}
+}
+
+// test parseEpoch (both green and red paths)
+func TestParseEpoch(t *testing.T) {
+	var tests = []struct {
+		name         string        // name/summary of test
+		leTimeB      string        // 1st parameter
+		unit         time.Duration // 2nd parameter
+		expectedTime time.Time     // expected return value (zero if expecting an error)
+	}{
+		{
+			name:         "test: seconds",
+			leTimeB:      "1716200000",
+			unit:         time.Second,
+			expectedTime: time.Unix(1716200000, 0),
+		},
+		{
+			name:         "test: fractional seconds",
+			leTimeB:      "1716200000.123",
+			unit:         time.Second,
+			expectedTime: time.Unix(1716200000, 123000000),
+		},
+		{
+			name:         "test: fractional seconds beyond nanosecond precision",
+			leTimeB:      "1716200000.1234567899",
+			unit:         time.Second,
+			expectedTime: time.Unix(1716200000, 123456789),
+		},
+		{
+			name:         "test: milliseconds",
+			leTimeB:      "1716200000123",
+			unit:         time.Millisecond,
+			expectedTime: time.Unix(1716200000, 123000000),
+		},
+		{
+			name:         "test: fractional milliseconds",
+			leTimeB:      "1716200000123.5",
+			unit:         time.Millisecond,
+			expectedTime: time.Unix(1716200000, 123500000),
+		},
+		{
+			name:         "test: microseconds",
+			leTimeB:      "1716200000123456",
+			unit:         time.Microsecond,
+			expectedTime: time.Unix(1716200000, 123456000),
+		},
+		{
+			name:         "test: nanoseconds",
+			leTimeB:      "1716200000123456789",
+			unit:         time.Nanosecond,
+			expectedTime: time.Unix(1716200000, 123456789),
+		},
+		{
+			name:         "test: negative fractional seconds",
+			leTimeB:      "-0.5",
+			unit:         time.Second,
+			expectedTime: time.Unix(0, -500000000),
+		},
+		{
+			name:    "test: not a number",
+			leTimeB: "Jun 15, 2010",
+			unit:    time.Second,
+		},
+		{
+			name:    "test: bad fractional part",
+			leTimeB: "1716200000.12a",
+			unit:    time.Second,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			leTime, err := parseEpoch([]byte(test.leTimeB), test.unit)
+			if test.expectedTime.IsZero() {
+				if err == nil || !leTime.IsZero() {
+					t.Errorf("Got (%v, %v), want (zero time, error)", leTime, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !leTime.Equal(test.expectedTime) || leTime.Location() != time.UTC {
+				t.Errorf("Got time: %v, want time: %v UTC", leTime, test.expectedTime.UTC())
+			}
+		})
+	}
+}
+
+// test timeParser.parse with several layouts; each time stamp is parsed in turn
+// with the same timeParser, so the layout that last succeeded is carried over
+func TestParseLayouts(t *testing.T) {
+	re := regexp.MustCompile(`^\[([^\]]+)\]`)
+	p := newTimeParser(re, &SearchCriteria{
+		LeTimeFormat:  `02/Jan/2006:15:04:05`,
+		LeTimeFormats: []string{`2006-01-02T15:04:05.000Z07:00`, EpochSeconds},
+	}, time.Time{}, false)
+
+	var tests = []struct {
+		name         string    // name/summary of test
+		line         string    // 1st parameter
+		expectedTime time.Time // expected return value (zero if expecting an error)
+		expectedLast int       // expected index of the layout that last succeeded
+	}{
+		{
+			name:         "test: first layout",
+			line:         "[01/May/2024:12:00:00] a",
+			expectedTime: time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC),
+			expectedLast: 0,
+		},
+		{
+			name:         "test: second layout",
+			line:         "[2024-05-01T13:00:00.250Z] b",
+			expectedTime: time.Date(2024, time.May, 1, 13, 0, 0, 250000000, time.UTC),
+			expectedLast: 1,
+		},
+		{
+			name:         "test: second layout again",
+			line:         "[2024-05-01T14:00:00.000Z] c",
+			expectedTime: time.Date(2024, time.May, 1, 14, 0, 0, 0, time.UTC),
+			expectedLast: 1,
+		},
+		{
+			name:         "test: epoch layout",
+			line:         "[1714575600] d",
+			expectedTime: time.Date(2024, time.May, 1, 15, 0, 0, 0, time.UTC),
+			expectedLast: 2,
+		},
+		{
+			name:         "test: first layout after the others",
+			line:         "[01/May/2024:16:00:00] e",
+			expectedTime: time.Date(2024, time.May, 1, 16, 0, 0, 0, time.UTC),
+			expectedLast: 0,
+		},
+		{
+			name:         "test: no layout matches",
+			line:         "[May 1 17:00:00] f",
+			expectedLast: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			line := []byte(test.line)
+			leTime, err := p.parse(line, re.FindSubmatchIndex(line), 0)
+			if test.expectedTime.IsZero() {
+				if !errors.Is(err, ErrLeTimeFormatMismatch) {
+					t.Errorf("Got error: %v, want error that is: %v", err, ErrLeTimeFormatMismatch)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if !leTime.Equal(test.expectedTime) {
+				t.Errorf("Got time: %v, want time: %v", leTime, test.expectedTime)
+			}
+			if p.last[0] != test.expectedLast {
+				t.Errorf("Got last layout: %d, want last layout: %d", p.last[0], test.expectedLast)
+			}
+		})
+	}
+}
+
+// test lePatterns with single and combined start patterns (see
+// combineStartPatterns)
+func TestLePatterns(t *testing.T) {
+	combined, err := combineStartPatterns([]StartPattern{
+		{Pattern: `^(?P<ts>\S+) (?P<level>\w+)`},
+		{Pattern: `^\[(\S+)\]`},
+		{Pattern: `^GC`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name             string      // name/summary of test
+		leStartPattern   string      // 1st parameter (compiled)
+		expectedPatterns []lePattern // expected return value
+	}{
+		{
+			name:             "test: single pattern",
+			leStartPattern:   apacheStartPattern,
+			expectedPatterns: []lePattern{{group: 0, numSubexp: 1, timeIndex: -1}},
+		},
+		{
+			name:             "test: single pattern with ts group",
+			leStartPattern:   `^(\w+) (?P<ts>\S+)`,
+			expectedPatterns: []lePattern{{group: 0, numSubexp: 2, timeIndex: 2}},
+		},
+		{
+			name:           "test: combined patterns",
+			leStartPattern: combined,
+			expectedPatterns: []lePattern{
+				{group: 1, numSubexp: 2, timeIndex: 2},
+				{group: 4, numSubexp: 1, timeIndex: -1},
+				{group: 6, numSubexp: 0, timeIndex: -1},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patterns := lePatterns(regexp.MustCompile(test.leStartPattern))
+			if fmt.Sprint(patterns) != fmt.Sprint(test.expectedPatterns) {
+				t.Errorf("Got patterns: %+v, want patterns: %+v", patterns, test.expectedPatterns)
+			}
+		})
+	}
+}
+
+// test trimTrailingNewline (greenpaths only). The files are held in memory, as
+// trimTrailingNewline reads from an io.ReaderAt.
+func TestTrimTrailingNewline(t *testing.T) {
+	// define tests
+	var tests = []struct {
+		name         string        // name/summary of test
+		file         string        // 1st parameter (2nd parameter is its length)
+		newlines     NewlinePolicy // 3rd parameter
+		expectedSize int64         // expected first return value
+	}{
+		{"test: empty file", "", NewlineAuto, 0},
+		{"test: unix newline only", "\n", NewlineAuto, 0},
+		{"test: windows newline only", "\r\n", NewlineAuto, 0},
+		{"test: single char", "a", NewlineAuto, 1},
+		{"test: unix newline suffix", "line1\nline2\n", NewlineAuto, 11},
+		{"test: windows newline suffix", "line1\r\nline2\r\n", NewlineAuto, 12},
+		{"test: no newline suffix", "line1\nline2", NewlineAuto, 11},
+		{"test: only one newline is trimmed", "line1\n\n", NewlineAuto, 6},
+		{"test: windows newline suffix, LF only", "line1\r\nline2\r\n", NewlineLF, 13},
+	}
+
+	// iterate over tests
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			size, err := trimTrailingNewline(strings.NewReader(test.file), int64(len(test.file)),
+				test.newlines)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size != test.expectedSize {
+				t.Errorf("Got size: %d, want size: %d", size, test.expectedSize)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package reversesearch
+
+/* The rotated log set functions are contained in this file:
+- compressionExt
+- rotationIndex
+- isRotation
+- reverseSearchFile
+- RotatedFiles (exported)
+- ReverseSearchRotated (exported Searcher method)
+- ReverseSearchRotatedWithOptions (exported)
+- ReverseSearchRotatedContext (exported)
+- ReverseSearchRotated (exported)
+
+A rotated log set is the current log file plus its rotations, e.g. access.log,
+access.log.1, access.log.2.gz. ReverseSearchRotated searches them newest to
+oldest as though they were one continuous log file.
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// compressionExts are the file extensions of compressed rotations
+var compressionExts = []string{".gz", ".zst", ".bz2", ".xz"}
+
+// compressionExt returns the compression extension of filePath, or "" if the
+// file is not compressed
+func compressionExt(filePath string) string {
+	for _, ext := range compressionExts {
+		if strings.HasSuffix(filePath, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// maxRotationDigits is the number of digits beyond which a numeric suffix is
+// taken to be a date (e.g. access.log.20240501) rather than a rotation number
+const maxRotationDigits = 6
+
+// rotationIndex returns the rotation number of filePath, i.e. 2 for
+// access.log.2 or access.log.2.gz. Files without a numeric suffix, such as the
+// current log file, or rotations named after the date, are given 0.
+func rotationIndex(filePath string) int {
+	name := strings.TrimSuffix(filePath, compressionExt(filePath))
+	suffix := name[strings.LastIndexByte(name, '.')+1:]
+	if len(suffix) > maxRotationDigits {
+		return 0
+	}
+	index, err := strconv.Atoi(suffix)
+	if err != nil || index < 0 {
+		return 0
+	}
+	return index
+}
+
+// rotationSuffix matches the suffix of a rotation's file name (after the name
+// of the current log file and any compression extension), which is either a
+// rotation number (e.g. .1) or a date (e.g. -20240501 or .2024-05-01)
+var rotationSuffix = regexp.MustCompile(`^[.-]\d+(?:[-_]\d+)*$`)
+
+// isRotation reports whether filePath is a rotation of the log file at
+// basePath, i.e. whether it's named after basePath with a numeric or date
+// suffix, optionally followed by a compression extension. Other files named
+// after basePath, such as access.log.lock or access.log.swp, are not.
+func isRotation(filePath, basePath string) bool {
+	suffix := strings.TrimPrefix(filePath, basePath)
+	return rotationSuffix.MatchString(strings.TrimSuffix(suffix, compressionExt(suffix)))
+}
+
+// reverseSearchFile opens the file at filePath (decompressing it if need be)
+// and reverse searches it with opts
+func reverseSearchFile(ctx context.Context, filePath string, leStartRegexp *regexp.Regexp,
+	regexps []*regexp.Regexp, searchCriteria *SearchCriteria,
+	handler EntryHandler, opts *Options) (int, bool, error) {
+
+	file, closeFile, err := openLogFile(filePath, opts)
+	if err != nil {
+		return -1, false, err
+	}
+	defer closeFile()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return -1, false, err
+	}
+
+	return reverseSearch(ctx, file, fileInfo.Size(), leStartRegexp, regexps,
+		searchCriteria, handler, opts)
+}
+
+// RotatedFiles returns the files that make up a rotated log set, ordered from
+// newest to oldest. pattern is either the name of the current log file (e.g.
+// /var/log/access.log), in which case its rotations are the files named
+// after it with a suffix such as .1, .2.gz or -20240501 (see isRotation), or a
+// glob pattern as accepted by filepath.Glob. The files are ordered by their
+// numeric rotation suffix (see rotationIndex), and then by modification time,
+// so rotations named after the date are ordered newest first.
+func RotatedFiles(pattern string) ([]string, error) {
+	var filePaths []string
+	if strings.ContainsAny(pattern, `*?[`) {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		filePaths = matches
+	} else {
+		if _, err := os.Stat(pattern); err == nil {
+			filePaths = append(filePaths, pattern)
+		}
+		for _, suffixPattern := range []string{".*", "-*"} {
+			matches, err := filepath.Glob(pattern + suffixPattern)
+			if err != nil {
+				return nil, err
+			}
+			for _, match := range matches {
+				if isRotation(match, pattern) {
+					filePaths = append(filePaths, match)
+				}
+			}
+		}
+	}
+	if len(filePaths) == 0 {
+		// return the same error that opening a non existent log file would
+		_, err := os.Open(pattern)
+		if err == nil { // sanity check
+			err = errors.New("no files match " + pattern)
+		}
+		return nil, err
+	}
+
+	// work out each file's modification time
+	modTimes := make(map[string]time.Time, len(filePaths))
+	for _, filePath := range filePaths {
+		fileInfo, err := os.Stat(filePath)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[filePath] = fileInfo.ModTime()
+	}
+
+	sort.SliceStable(filePaths, func(i, j int) bool {
+		a, b := filePaths[i], filePaths[j]
+		if rotationIndex(a) != rotationIndex(b) {
+			return rotationIndex(a) < rotationIndex(b)
+		}
+		return modTimes[a].After(modTimes[b])
+	})
+	return filePaths, nil
+}
+
+// ReverseSearchRotated reverse searches a rotated log set (see the package
+// level ReverseSearchRotated) with the Searcher's search criteria and options.
+// An empty file in the set is only reported with a FileIsEmpty error (when
+// EmptyFileIsError is set) if every file in the set is empty. If handler is
+// nil, matching log entries are printed to STDOUT.
+func (s *Searcher) ReverseSearchRotated(ctx context.Context, pattern string,
+	handler EntryHandler) (int, error) {
+	if handler == nil {
+		handler = outputEntryHandler(nil)
+	}
+
+	// find the files in the rotated log set
+	filePaths, err := RotatedFiles(pattern)
+	if err != nil {
+		return -1, err
+	}
+
+	// an empty file in the set is only an error if every file in it is empty
+	fileOpts := *s.opts
+	fileOpts.EmptyFileIsError = false
+
+	exitStatus := 1
+	for _, filePath := range filePaths {
+		fileExitStatus, abort, err := reverseSearchFile(ctx, filePath, s.leStartRegexp,
+			s.regexps, &s.searchCriteria, handler, &fileOpts)
+		if err != nil {
+			return -1, fmt.Errorf("%s: %w", filePath, err)
+		}
+		if fileExitStatus == 0 {
+			exitStatus = 0
+		}
+		if abort {
+			break
+		}
+	}
+	if exitStatus == 1 && s.opts.EmptyFileIsError {
+		return -1, ErrFileIsEmpty
+	}
+	return exitStatus, nil
+}
+
+// ReverseSearchRotatedWithOptions is the same as ReverseSearchRotatedContext,
+// except that the search uses the settings in opts (see
+// ReverseSearchWithOptions), and matching log entries are passed to handler as
+// LogEntry structs. A nil opts means the defaults are used.
+func ReverseSearchRotatedWithOptions(ctx context.Context, pattern string,
+	searchCriteria *SearchCriteria, opts *Options, handler EntryHandler) (int, error) {
+	searcher, err := NewSearcher(searchCriteria, opts)
+	if err != nil {
+		return -1, err
+	}
+	return searcher.ReverseSearchRotated(ctx, pattern, handler)
+}
+
+// ReverseSearchRotatedContext is the same as ReverseSearchRotated, except that
+// the search stops once ctx is done (see ReverseSearchContext). The
+// *CanceledError returned is wrapped with the path of the file being searched
+// at the time, and its Offset is relative to that file.
+func ReverseSearchRotatedContext(ctx context.Context, pattern string,
+	searchCriteria *SearchCriteria, outputHandler OutputHandler) (int, error) {
+	return ReverseSearchRotatedWithOptions(ctx, pattern, searchCriteria, nil,
+		outputEntryHandler(outputHandler))
+}
+
+// ReverseSearchRotated reverse searches a rotated log set (see RotatedFiles for
+// the meaning of pattern) as though it were one continuous log file, starting
+// with the newest log entry in the newest file. When a log entry fails
+// searchCriteria.FromTime, the search ends, and older files in the set are not
+// opened. Rotations compressed with gzip, bzip2 or zstd are decompressed as
+// they're reached (see ReverseSearch). Return values are the same as those of
+// ReverseSearch, with an exit status of 1 only if every file in the set is empty.
+func ReverseSearchRotated(pattern string, searchCriteria *SearchCriteria,
+	outputHandler OutputHandler) (int, error) {
+	return ReverseSearchRotatedContext(context.Background(), pattern, searchCriteria,
+		outputHandler)
+}
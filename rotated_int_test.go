@@ -0,0 +1,284 @@
+package reversesearch_test
+
+/* Integration tests for RotatedFiles and ReverseSearchRotated. The rotated log
+sets are created in a temporary directory by splitting up the access.log test
+file. */
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/freebiesoft/reversesearch"
+)
+
+// createRotatedLogSet splits the lines of access.log into 3 files, access.log
+// (newest), access.log.1 and access.log.2 (oldest), within dir. The path of
+// access.log is returned.
+func createRotatedLogSet(dir string) string {
+	contents, err := ioutil.ReadFile(accessLog)
+	check(err)
+	lines := strings.SplitAfter(string(contents), "\n")
+	third := len(lines) / 3
+
+	basePath := filepath.Join(dir, "access.log")
+	check(ioutil.WriteFile(basePath+".2", []byte(strings.Join(lines[:third], "")), 0644))
+	check(ioutil.WriteFile(basePath+".1", []byte(strings.Join(lines[third:2*third], "")), 0644))
+	check(ioutil.WriteFile(basePath, []byte(strings.Join(lines[2*third:], "")), 0644))
+	return basePath
+}
+
+// TestRotatedFiles checks the order of the files in a rotated log set
+func TestRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	basePath := filepath.Join(dir, "app.log")
+	for _, name := range []string{"app.log.10.gz", "app.log", "app.log.2.gz", "app.log.1"} {
+		check(ioutil.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+
+	// date suffixed rotations are ordered by modification time
+	check(ioutil.WriteFile(filepath.Join(dir, "other.log-20240501"), nil, 0644))
+	check(ioutil.WriteFile(filepath.Join(dir, "other.log-20240502"), nil, 0644))
+	check(ioutil.WriteFile(filepath.Join(dir, "other.log"), nil, 0644))
+	now := time.Now()
+	check(os.Chtimes(filepath.Join(dir, "other.log-20240501"), now, now.Add(-2*time.Hour)))
+	check(os.Chtimes(filepath.Join(dir, "other.log-20240502"), now, now.Add(-time.Hour)))
+	check(ioutil.WriteFile(filepath.Join(dir, "dotted.log.20240501"), nil, 0644))
+	check(ioutil.WriteFile(filepath.Join(dir, "dotted.log.20240502.gz"), nil, 0644))
+	check(ioutil.WriteFile(filepath.Join(dir, "dotted.log"), nil, 0644))
+	check(os.Chtimes(filepath.Join(dir, "dotted.log.20240501"), now, now.Add(-2*time.Hour)))
+	check(os.Chtimes(filepath.Join(dir, "dotted.log.20240502.gz"), now, now.Add(-time.Hour)))
+
+	// files named after the log file that aren't rotations are left out
+	for _, name := range []string{"stray.log", "stray.log.1", "stray.log.2.gz", "stray.log.lock",
+		"stray.log.swp", "stray.log.bak", "stray.log.1.swp", "stray.log-old"} {
+		check(ioutil.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+
+	var tests = []struct {
+		name     string
+		pattern  string
+		expected []string
+	}{
+		{"test 1: base name", basePath,
+			[]string{"app.log", "app.log.1", "app.log.2.gz", "app.log.10.gz"}},
+		{"test 2: glob", filepath.Join(dir, "app.log*"),
+			[]string{"app.log", "app.log.1", "app.log.2.gz", "app.log.10.gz"}},
+		{"test 3: date suffixes", filepath.Join(dir, "other.log"),
+			[]string{"other.log", "other.log-20240502", "other.log-20240501"}},
+		{"test 4: dot separated date suffixes", filepath.Join(dir, "dotted.log"),
+			[]string{"dotted.log", "dotted.log.20240502.gz", "dotted.log.20240501"}},
+		{"test 5: stray siblings", filepath.Join(dir, "stray.log"),
+			[]string{"stray.log", "stray.log.1", "stray.log.2.gz"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePaths, err := RotatedFiles(test.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := make([]string, len(filePaths))
+			for i, filePath := range filePaths {
+				actual[i] = filepath.Base(filePath)
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("Got %v, want %v", actual, test.expected)
+			}
+		})
+	}
+}
+
+// TestReverseSearchRotated checks that a rotated log set is searched as though
+// it were one continuous log file
+func TestReverseSearchRotated(t *testing.T) {
+	StartBufLen = 256
+	basePath := createRotatedLogSet(t.TempDir())
+
+	var tests = []struct {
+		name           string
+		searchCriteria SearchCriteria
+	}{
+		{
+			name: "test 1: regexp match across all files",
+			searchCriteria: SearchCriteria{
+				Regexps:        []string{`/modules/mod_araticlhess1/mod_araticlhess1\.php`},
+				LeStartPattern: apacheStartPattern,
+			},
+		},
+		{
+			name: "test 2: fromTime constraint",
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(apacheTimeFormat, `23/Sep/2019:00:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var expected []string
+			_, err := ReverseSearch(accessLog, &test.searchCriteria,
+				func(logEntry []byte) { expected = append(expected, string(logEntry)) })
+			check(err)
+
+			var actual []string
+			exitStatus, err := ReverseSearchRotated(basePath, &test.searchCriteria,
+				func(logEntry []byte) { actual = append(actual, string(logEntry)) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Got %d matches, want %d matches", len(actual), len(expected))
+			}
+			if exitStatus != 0 {
+				t.Errorf("Got exit status: %d, want exit status: 0", exitStatus)
+			}
+		})
+	}
+}
+
+// TestReverseSearchRotatedCompressed checks that compressed rotations are
+// decompressed and searched
+func TestReverseSearchRotatedCompressed(t *testing.T) {
+	StartBufLen = 256
+	basePath := createRotatedLogSet(t.TempDir())
+
+	// gzip the oldest rotation
+	contents, err := ioutil.ReadFile(basePath + ".2")
+	check(err)
+	gzipFile, err := os.Create(basePath + ".2.gz")
+	check(err)
+	gzipWriter := gzip.NewWriter(gzipFile)
+	_, err = gzipWriter.Write(contents)
+	check(err)
+	check(gzipWriter.Close())
+	check(gzipFile.Close())
+	check(os.Remove(basePath + ".2"))
+
+	searchCriteria := SearchCriteria{LeStartPattern: apacheStartPattern}
+	var expected []string
+	_, err = ReverseSearch(accessLog, &searchCriteria,
+		func(logEntry []byte) { expected = append(expected, string(logEntry)) })
+	check(err)
+
+	var actual []string
+	_, err = ReverseSearchRotated(basePath, &searchCriteria,
+		func(logEntry []byte) { actual = append(actual, string(logEntry)) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Got %d matches, want %d matches", len(actual), len(expected))
+	}
+}
+
+// TestReverseSearchRotatedStopsAtFromTime checks that older files are not
+// searched once a log entry fails FromTime; the oldest file is replaced with
+// one that would cause an error if it were searched
+func TestReverseSearchRotatedStopsAtFromTime(t *testing.T) {
+	StartBufLen = 256
+	basePath := createRotatedLogSet(t.TempDir())
+	check(ioutil.WriteFile(basePath+".2", []byte("not a log entry\n"), 0644))
+
+	searchCriteria := SearchCriteria{
+		FromTime:       parseTime(apacheTimeFormat, `23/Sep/2019:00:00:00 +0200`),
+		LeStartPattern: apacheStartPattern,
+		LeTimeFormat:   apacheTimeFormat,
+	}
+	exitStatus, err := ReverseSearchRotated(basePath, &searchCriteria, func([]byte) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exitStatus != 0 {
+		t.Errorf("Got exit status: %d, want exit status: 0", exitStatus)
+	}
+
+	// without a fromTime constraint the oldest file is searched
+	searchCriteria.FromTime = time.Time{}
+	_, err = ReverseSearchRotated(basePath, &searchCriteria, func([]byte) {})
+	if err == nil || !strings.Contains(err.Error(), NoLogEntriesInFile) {
+		t.Errorf("Got error: %v, want error that contains: \"%s\"", err,
+			NoLogEntriesInFile)
+	}
+}
+
+// TestReverseSearchRotatedWithOptions checks that rotated searches honour the
+// Options they're given, and that an empty set is only an error when
+// EmptyFileIsError is set
+func TestReverseSearchRotatedWithOptions(t *testing.T) {
+	StartBufLen = 256
+	basePath := createRotatedLogSet(t.TempDir())
+	contents, err := ioutil.ReadFile(basePath + ".2")
+	check(err)
+	check(ioutil.WriteFile(basePath+".2", append([]byte("preamble\n"), contents...), 0644))
+	emptyPath := filepath.Join(t.TempDir(), "empty.log")
+	check(ioutil.WriteFile(emptyPath, nil, 0644))
+	check(ioutil.WriteFile(emptyPath+".1", []byte("\n"), 0644))
+	searchCriteria := SearchCriteria{LeStartPattern: apacheStartPattern}
+
+	var tests = []struct {
+		name               string
+		pattern            string
+		opts               *Options
+		expectedPreamble   bool
+		expectedExitStatus int
+		expectedErr        error
+	}{
+		{
+			name:               "test 1: preamble is an error by default",
+			pattern:            basePath,
+			expectedExitStatus: -1,
+			expectedErr:        ErrNoMoreLogEntries,
+		},
+		{
+			name:             "test 2: preamble entry",
+			pattern:          basePath,
+			opts:             &Options{StartBufLen: 64, Preamble: PreambleEntry},
+			expectedPreamble: true,
+		},
+		{
+			name:               "test 3: empty set",
+			pattern:            emptyPath,
+			expectedExitStatus: 1,
+		},
+		{
+			name:               "test 4: empty set is an error",
+			pattern:            emptyPath,
+			opts:               &Options{EmptyFileIsError: true},
+			expectedExitStatus: -1,
+			expectedErr:        ErrFileIsEmpty,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var preamble []byte
+			exitStatus, err := ReverseSearchRotatedWithOptions(context.Background(),
+				test.pattern, &searchCriteria, test.opts, func(logEntry LogEntry) (Control, error) {
+					if logEntry.Preamble {
+						preamble = append([]byte(nil), logEntry.Bytes...)
+					}
+					return Continue, nil
+				})
+			if !errors.Is(err, test.expectedErr) {
+				t.Errorf("Got error: %v, want error that is: %v", err, test.expectedErr)
+			}
+			if exitStatus != test.expectedExitStatus {
+				t.Errorf("Got exit status: %d, want exit status: %d", exitStatus,
+					test.expectedExitStatus)
+			}
+			if test.expectedPreamble && string(preamble) != "preamble" {
+				t.Errorf("Got preamble: %q, want preamble: %q", preamble, "preamble")
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package reversesearch
+
+/* The search planning functions are contained in this file:
+- sampleLeTime
+- planSearch
+- PlanSearch (exported)
+- Search (exported)
+
+Search is the wrapping function mentioned in the README; it guesses which of
+ReverseSearch, ForwardSearch and BinarySearch will be the most performant for
+the search criteria and log file, and then delegates to it.
+*/
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Strategy identifies the traversal strategy used to search a log file
+type Strategy int
+
+const (
+	// ReverseStrategy traverses the log file from end to beginning (see ReverseSearch)
+	ReverseStrategy Strategy = iota
+
+	// ForwardStrategy traverses the log file from beginning to end (see ForwardSearch)
+	ForwardStrategy
+
+	// BinaryStrategy bisects the log file to find FromTime, then traverses the log
+	// file forwards (see BinarySearch)
+	BinaryStrategy
+)
+
+// String returns the name of the search function that implements the strategy
+func (s Strategy) String() string {
+	switch s {
+	case ReverseStrategy:
+		return "ReverseSearch"
+	case ForwardStrategy:
+		return "ForwardSearch"
+	case BinaryStrategy:
+		return "BinarySearch"
+	}
+	return "Strategy(" + strconv.Itoa(int(s)) + ")"
+}
+
+// SearchPlan describes the strategy Search chose for a log file, along with
+// a human readable explanation of why it was chosen. Note that the strategy
+// determines the order in which matching log entries are passed to the output
+// handler; newest first for ReverseStrategy, oldest first otherwise.
+type SearchPlan struct {
+	Strategy Strategy
+	Reason   string
+}
+
+// sampleLeTime looks for log entries that start within buf and returns the
+// time of logging of the first of them, or the last of them if last is set.
+// Lines that may have been cut off by the beginning or end of buf can be
+// ignored with skipFirstLine and skipLastLine respectively. A zero time is
+// returned if no log entries are found. bufOffset is the position of buf[0] in
+// the log file. Log entries whose time stamps can't be parsed are ignored
+// unless opts.BadTimes is BadTimeAbort.
+func sampleLeTime(buf []byte, bufOffset int64, skipFirstLine bool, skipLastLine bool,
+	last bool, leStartRegexp *regexp.Regexp, timeParser *timeParser,
+	opts *Options) (time.Time, error) {
+
+	var leTime time.Time
+	lineStart := 0
+	for {
+		lineEnd, nextLineStart, found := nextLine(buf, lineStart, len(buf), opts.Newlines)
+		if !found && skipLastLine {
+			break
+		}
+
+		if skipFirstLine {
+			skipFirstLine = false
+		} else if matches := leStartRegexp.FindSubmatchIndex(
+			buf[lineStart:lineEnd]); matches != nil {
+			t, err := timeParser.parse(buf[lineStart:lineEnd], matches, bufOffset+int64(lineStart))
+			switch {
+			case err == nil && !last:
+				return t, nil
+			case err == nil:
+				leTime = t
+			case opts.BadTimes == BadTimeAbort || !errors.Is(err, ErrLeTimeFormatMismatch):
+				return time.Time{}, err
+			}
+		}
+
+		if !found {
+			break
+		}
+		lineStart = nextLineStart
+	}
+	return leTime, nil
+}
+
+// planSearch chooses a strategy for searchCriteria based on which of FromTime
+// and UntilTime are set, the size of the file, and the time stamps of the first
+// and last log entries in the file (which are sampled from a single buf load at
+// each end of the file). fileSize should already have had its trailing newline
+// discounted, and opts must already have been resolved (see resolveOptions).
+func planSearch(file io.ReaderAt, fileSize int64, leStartRegexp *regexp.Regexp,
+	searchCriteria *SearchCriteria, opts *Options) (SearchPlan, error) {
+
+	fromTime, untilTime := searchCriteria.FromTime, searchCriteria.UntilTime
+
+	switch {
+	case fromTime.IsZero() && untilTime.IsZero():
+		return SearchPlan{ReverseStrategy,
+			"there are no time constraints, so the whole file must be searched"}, nil
+	case untilTime.IsZero():
+		return SearchPlan{ReverseStrategy,
+			"only FromTime is set, so the search can end upon reaching FromTime"}, nil
+	case fromTime.IsZero():
+		return SearchPlan{ForwardStrategy,
+			"only UntilTime is set, so the search can end upon reaching UntilTime"}, nil
+	case fileSize <= int64(opts.StartBufLen):
+		return SearchPlan{ReverseStrategy,
+			"the file fits into a single buf load, so there is nothing to gain by bisecting it"}, nil
+	}
+
+	// sample the time stamps of the first and last log entries in the file
+	sampleLen := int64(opts.StartBufLen)
+	if sampleLen > fileSize {
+		sampleLen = fileSize
+	}
+	buf := opts.getBuf(int(sampleLen))
+	defer opts.putBuf(buf)
+	timeParser := newTimeParser(leStartRegexp, searchCriteria, yearReference(file, opts), false)
+
+	if err := readAt(file, buf, 0); err != nil {
+		return SearchPlan{}, err
+	}
+	firstLeTime, err := sampleLeTime(buf, 0, false, sampleLen < fileSize, false,
+		leStartRegexp, timeParser, opts)
+	if err != nil {
+		return SearchPlan{}, err
+	}
+
+	if err := readAt(file, buf, fileSize-sampleLen); err != nil {
+		return SearchPlan{}, err
+	}
+	lastLeTime, err := sampleLeTime(buf, fileSize-sampleLen, sampleLen < fileSize, false, true,
+		leStartRegexp, timeParser, opts)
+	if err != nil {
+		return SearchPlan{}, err
+	}
+
+	switch {
+	case !firstLeTime.IsZero() && !fromTime.After(firstLeTime):
+		return SearchPlan{ForwardStrategy,
+			"the time range starts at the beginning of the file"}, nil
+	case !lastLeTime.IsZero() && untilTime.After(lastLeTime):
+		return SearchPlan{ReverseStrategy,
+			"the time range extends to the end of the file"}, nil
+	}
+	return SearchPlan{BinaryStrategy,
+		"the time range is bounded at both ends, so bisecting the file to find FromTime " +
+			"avoids reading the bytes outside of it"}, nil
+}
+
+// PlanSearch returns the strategy that Search would use to search the log file
+// specified by filePath with searchCriteria, without carrying out the search.
+func PlanSearch(filePath string, searchCriteria *SearchCriteria) (SearchPlan, error) {
+	searcher, err := NewSearcher(searchCriteria, nil)
+	if err != nil {
+		return SearchPlan{}, err
+	}
+	return searcher.PlanSearch(filePath)
+}
+
+// Search searches the log file specified by filePath for matching log entries
+// using whichever of ReverseSearch, ForwardSearch or BinarySearch is expected to
+// be the most performant (see PlanSearch). The chosen strategy is returned
+// along with the return values of the search function it delegated to:
+//
+// 1) plan (SearchPlan): the strategy used, and the reason for choosing it
+//
+// 2) exitStatus (int): -1 indicates an error was found, 0 indicates normal
+// execution without issues, 1 indicates file is empty (not considered an error)
+//
+// 3) err (error)
+func Search(filePath string, searchCriteria *SearchCriteria,
+	outputHandler OutputHandler) (SearchPlan, int, error) {
+	searcher, err := NewSearcher(searchCriteria, nil)
+	if err != nil {
+		return SearchPlan{}, -1, err
+	}
+	return searcher.Search(filePath, outputEntryHandler(outputHandler))
+}
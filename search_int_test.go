@@ -0,0 +1,156 @@
+package reversesearch_test
+
+/* Integration tests for PlanSearch and Search. */
+
+import (
+	"testing"
+
+	. "github.com/freebiesoft/reversesearch"
+)
+
+// TestSearch checks the strategy chosen by Search for a variety of search
+// criteria, and that Search passes on the same log entries as the search
+// function it delegates to
+func TestSearch(t *testing.T) {
+	StartBufLen = 256
+
+	var tests = []struct {
+		name             string         // test name (also description summary)
+		filePath         string         // path of file to be searched
+		searchCriteria   SearchCriteria // second parameter of Search
+		expectedStrategy Strategy       // strategy Search should choose
+	}{
+		{
+			name:     "test 1: no time constraints",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				LeStartPattern: apacheStartPattern,
+			},
+			expectedStrategy: ReverseStrategy,
+		},
+		{
+			name:     "test 2: fromTime only",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(apacheTimeFormat, `23/Sep/2019:00:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+			expectedStrategy: ReverseStrategy,
+		},
+		{
+			name:     "test 3: untilTime only",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				UntilTime:      parseTime(apacheTimeFormat, `22/Sep/2019:00:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+			expectedStrategy: ForwardStrategy,
+		},
+		{
+			name:     "test 4: time range in the middle of the file",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(apacheTimeFormat, `23/Sep/2019:00:00:00 +0200`),
+				UntilTime:      parseTime(apacheTimeFormat, `23/Sep/2019:00:30:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+			expectedStrategy: BinaryStrategy,
+		},
+		{
+			name:     "test 5: time range starts before the first log entry",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(apacheTimeFormat, `01/Jan/2019:00:00:00 +0200`),
+				UntilTime:      parseTime(apacheTimeFormat, `21/Sep/2019:21:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+			expectedStrategy: ForwardStrategy,
+		},
+		{
+			name:     "test 6: time range ends after the last log entry",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(apacheTimeFormat, `23/Sep/2019:16:00:00 +0200`),
+				UntilTime:      parseTime(apacheTimeFormat, `01/Jan/2020:00:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+			expectedStrategy: ReverseStrategy,
+		},
+		{
+			name:     "test 7: time range in a small file",
+			filePath: singleLog,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(odlTimeFormat, `Jun 15, 2010 2:00:00 AM IST`),
+				UntilTime:      parseTime(odlTimeFormat, `Jun 16, 2010 2:00:00 AM IST`),
+				LeStartPattern: odlStartPattern,
+				LeTimeFormat:   odlTimeFormat,
+			},
+			expectedStrategy: ReverseStrategy,
+		},
+		{
+			name:     "test 8: untilTime before the first log entry",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				UntilTime:      parseTime(apacheTimeFormat, `21/Sep/2019:00:00:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+			expectedStrategy: ForwardStrategy,
+		},
+		{
+			name:     "test 9: time range between two log entries",
+			filePath: accessLog,
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(apacheTimeFormat, `22/Sep/2019:10:00:00 +0200`),
+				UntilTime:      parseTime(apacheTimeFormat, `22/Sep/2019:10:30:00 +0200`),
+				LeStartPattern: apacheStartPattern,
+				LeTimeFormat:   apacheTimeFormat,
+			},
+			expectedStrategy: BinaryStrategy,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var expectedCount int
+			var err error
+			countHandler := func(logEntry []byte) { expectedCount++ }
+			switch test.expectedStrategy {
+			case ForwardStrategy:
+				_, err = ForwardSearch(test.filePath, &test.searchCriteria, countHandler)
+			case BinaryStrategy:
+				_, err = BinarySearch(test.filePath, &test.searchCriteria, countHandler)
+			default:
+				_, err = ReverseSearch(test.filePath, &test.searchCriteria, countHandler)
+			}
+			check(err)
+
+			var actualCount int
+			plan, exitStatus, err := Search(test.filePath, &test.searchCriteria,
+				func(logEntry []byte) { actualCount++ })
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if plan.Strategy != test.expectedStrategy {
+				t.Errorf("Got strategy: %s (%s), want strategy: %s", plan.Strategy,
+					plan.Reason, test.expectedStrategy)
+			}
+			if plan.Reason == "" {
+				t.Error("plan has no reason")
+			}
+			if actualCount != expectedCount {
+				t.Errorf("Got %d matches, want %d matches", actualCount, expectedCount)
+			}
+			if exitStatus != 0 {
+				t.Errorf("Got exit status: %d, want exit status: 0", exitStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package reversesearch
+
+/* The Searcher type and its methods are contained in this file:
+- Searcher (type)
+- NewSearcher (exported)
+- copySearchCriteria
+- open (method)
+- trim (method)
+- forward (method)
+- binary (method)
+- plan (method)
+- ReverseSearchReaderAt (exported method)
+- ReverseSearch (exported method)
+- ForwardSearch (exported method)
+- BinarySearch (exported method)
+- PlanSearch (exported method)
+- Search (exported method)
+
+A Searcher validates and compiles its search criteria once, so that the same
+search can be carried out on many log files without repeating that work. The
+package level search functions are implemented with a single use Searcher.
+*/
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Searcher carries out the same search on any number of log files. Its search
+// criteria are validated and its regular expressions compiled once, when it is
+// created, and the buffers used to read log files are pooled and reused by
+// later searches. A Searcher is safe for concurrent use by multiple goroutines.
+type Searcher struct {
+	// searchCriteria is a deep copy of the search criteria the Searcher was
+	// created with (see copySearchCriteria), so changes made to the caller's
+	// SearchCriteria have no effect on it
+	searchCriteria SearchCriteria
+	leStartRegexp  *regexp.Regexp
+	regexps        []*regexp.Regexp
+	opts           *Options
+}
+
+// NewSearcher validates searchCriteria and compiles its regular expressions,
+// returning a Searcher that searches log files with them using the settings in
+// opts (nil meaning the defaults). An error is returned if searchCriteria is
+// invalid (see ReverseSearch) or a regular expression fails to compile.
+func NewSearcher(searchCriteria *SearchCriteria, opts *Options) (*Searcher, error) {
+	// validate parameters
+	if err := validateSearchCriteria(searchCriteria); err != nil {
+		return nil, err
+	}
+
+	// compile searchCriteria.LeStartPattern and searchCriteria.Regexps
+	leStartRegexp, regexps, err := compileSearchCriteria(searchCriteria)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := resolveOptions(opts)
+	resolved.bufPool = &sync.Pool{}
+	return &Searcher{
+		searchCriteria: copySearchCriteria(searchCriteria),
+		leStartRegexp:  leStartRegexp,
+		regexps:        regexps,
+		opts:           resolved,
+	}, nil
+}
+
+// copySearchCriteria returns a copy of searchCriteria that shares none of its
+// slices or maps, as they are read again by every search (e.g. LeTimeFormats
+// and LeTimeZones by newTimeParser)
+func copySearchCriteria(searchCriteria *SearchCriteria) SearchCriteria {
+	sc := *searchCriteria
+	sc.Regexps = append([]string(nil), searchCriteria.Regexps...)
+	sc.LeStartPatterns = append([]StartPattern(nil), searchCriteria.LeStartPatterns...)
+	sc.LeTimeFormats = append([]string(nil), searchCriteria.LeTimeFormats...)
+	if searchCriteria.LeTimeZones != nil {
+		sc.LeTimeZones = make(map[string]*time.Location, len(searchCriteria.LeTimeZones))
+		for name, location := range searchCriteria.LeTimeZones {
+			sc.LeTimeZones[name] = location
+		}
+	}
+	return sc
+}
+
+// open opens the log file at filePath (decompressing it if need be), returning
+// it along with its size and the function that closes it
+func (s *Searcher) open(filePath string) (*os.File, int64, func() error, error) {
+	file, closeFile, err := openLogFile(filePath, s.opts)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	// get file size
+	fileInfo, err := file.Stat()
+	if err != nil {
+		closeFile()
+		return nil, 0, nil, err
+	}
+	return file, fileInfo.Size(), closeFile, nil
+}
+
+// trim discounts the trailing newline from size, so the last log entry is
+// consistent with the last log entry found by reverseSearch. The exit status
+// is 1 (or -1 if the Searcher's options make an empty file an error) if there
+// are no bytes left, and 0 otherwise.
+func (s *Searcher) trim(file io.ReaderAt, size int64) (int64, int, error) {
+	fileSize, err := trimTrailingNewline(file, size, s.opts.Newlines)
+	if err != nil {
+		return fileSize, -1, err
+	}
+
+	// check file is not empty
+	if fileSize < 1 {
+		if fileSize < 0 { // sanity check
+			return fileSize, -1, errors.New("file size is less than 0")
+		}
+		if s.opts.EmptyFileIsError {
+			return fileSize, -1, ErrFileIsEmpty
+		}
+		return fileSize, 1, nil
+	}
+	return fileSize, 0, nil
+}
+
+// forward searches the size bytes of file forwards from the beginning
+func (s *Searcher) forward(file io.ReaderAt, size int64, handler EntryHandler) (int, error) {
+	fileSize, exitStatus, err := s.trim(file, size)
+	if err != nil || exitStatus != 0 {
+		return exitStatus, err
+	}
+	return forwardSearch(file, fileSize, 0, s.leStartRegexp, s.regexps, &s.searchCriteria,
+		handler, s.opts)
+}
+
+// binary bisects the size bytes of file to find FromTime, then searches
+// forwards from there
+func (s *Searcher) binary(file io.ReaderAt, size int64, handler EntryHandler) (int, error) {
+	fileSize, exitStatus, err := s.trim(file, size)
+	if err != nil || exitStatus != 0 {
+		return exitStatus, err
+	}
+
+	// find the first log entry that satisfies FromTime (less Tolerance, as log
+	// entries within Tolerance of FromTime may be out of order)
+	startOffset, err := seekFromTime(file, fileSize, s.leStartRegexp,
+		newTimeParser(s.leStartRegexp, &s.searchCriteria, yearReference(file, s.opts), false),
+		s.searchCriteria.FromTime.Add(-s.searchCriteria.Tolerance), s.opts)
+	if err != nil {
+		return -1, err
+	}
+	if startOffset >= fileSize {
+		// every log entry in the file was logged before FromTime
+		return 0, nil
+	}
+
+	return forwardSearch(file, fileSize, startOffset, s.leStartRegexp, s.regexps,
+		&s.searchCriteria, handler, s.opts)
+}
+
+// plan chooses a strategy for searching the size bytes of file
+func (s *Searcher) plan(file io.ReaderAt, size int64) (SearchPlan, error) {
+	// discount the trailing newline as the search functions do
+	fileSize, err := trimTrailingNewline(file, size, s.opts.Newlines)
+	if err != nil {
+		return SearchPlan{}, err
+	}
+	return planSearch(file, fileSize, s.leStartRegexp, &s.searchCriteria, s.opts)
+}
+
+// ReverseSearchReaderAt reverse searches the size bytes held by r (see
+// ReverseSearchReaderAt and ReverseSearchLogEntries). If handler is nil,
+// matching log entries are printed to STDOUT.
+func (s *Searcher) ReverseSearchReaderAt(ctx context.Context, r io.ReaderAt, size int64,
+	handler EntryHandler) (int, error) {
+	if handler == nil {
+		handler = outputEntryHandler(nil)
+	}
+	exitStatus, _, err := reverseSearch(ctx, r, size, s.leStartRegexp, s.regexps,
+		&s.searchCriteria, handler, s.opts)
+	return exitStatus, err
+}
+
+// ReverseSearch reverse searches the log file specified by filePath (see
+// ReverseSearchLogEntries). If handler is nil, matching log entries are
+// printed to STDOUT.
+func (s *Searcher) ReverseSearch(ctx context.Context, filePath string,
+	handler EntryHandler) (int, error) {
+	// open file (decompressing it if need be)
+	file, size, closeFile, err := s.open(filePath)
+	if err != nil {
+		return -1, err
+	}
+	defer closeFile()
+
+	return s.ReverseSearchReaderAt(ctx, file, size, handler)
+}
+
+// ForwardSearch searches the log file specified by filePath forwards (see
+// ForwardSearch). If handler is nil, matching log entries are printed to
+// STDOUT.
+func (s *Searcher) ForwardSearch(filePath string, handler EntryHandler) (int, error) {
+	if handler == nil {
+		handler = outputEntryHandler(nil)
+	}
+
+	// open file (decompressing it if need be)
+	file, size, closeFile, err := s.open(filePath)
+	if err != nil {
+		return -1, err
+	}
+	defer closeFile()
+
+	return s.forward(file, size, handler)
+}
+
+// BinarySearch bisects the log file specified by filePath to find FromTime,
+// then searches forwards from there (see BinarySearch). If handler is nil,
+// matching log entries are printed to STDOUT.
+func (s *Searcher) BinarySearch(filePath string, handler EntryHandler) (int, error) {
+	if s.searchCriteria.FromTime.IsZero() {
+		return -1, ErrNoFromTime
+	}
+	if handler == nil {
+		handler = outputEntryHandler(nil)
+	}
+
+	// open file (decompressing it if need be)
+	file, size, closeFile, err := s.open(filePath)
+	if err != nil {
+		return -1, err
+	}
+	defer closeFile()
+
+	return s.binary(file, size, handler)
+}
+
+// PlanSearch returns the strategy that Search would use to search the log file
+// specified by filePath (see PlanSearch), without carrying out the search
+func (s *Searcher) PlanSearch(filePath string) (SearchPlan, error) {
+	// open file (decompressing it if need be)
+	file, size, closeFile, err := s.open(filePath)
+	if err != nil {
+		return SearchPlan{}, err
+	}
+	defer closeFile()
+
+	return s.plan(file, size)
+}
+
+// Search searches the log file specified by filePath using whichever strategy
+// is expected to be the most performant (see Search). The log file is only
+// opened once, for both planning and searching. If handler is nil, matching
+// log entries are printed to STDOUT.
+func (s *Searcher) Search(filePath string, handler EntryHandler) (SearchPlan, int, error) {
+	if handler == nil {
+		handler = outputEntryHandler(nil)
+	}
+
+	// open file (decompressing it if need be)
+	file, size, closeFile, err := s.open(filePath)
+	if err != nil {
+		return SearchPlan{}, -1, err
+	}
+	defer closeFile()
+
+	plan, err := s.plan(file, size)
+	if err != nil {
+		return plan, -1, err
+	}
+
+	var exitStatus int
+	switch plan.Strategy {
+	case ForwardStrategy:
+		exitStatus, err = s.forward(file, size, handler)
+	case BinaryStrategy:
+		exitStatus, err = s.binary(file, size, handler)
+	default:
+		exitStatus, _, err = reverseSearch(context.Background(), file, size, s.leStartRegexp,
+			s.regexps, &s.searchCriteria, handler, s.opts)
+	}
+	return plan, exitStatus, err
+}
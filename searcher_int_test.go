@@ -0,0 +1,233 @@
+package reversesearch_test
+
+/* Integration tests for Searcher. Its results are compared with those of the
+package level search functions, which are assumed to be correct as they're
+tested separately. */
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/freebiesoft/reversesearch"
+)
+
+// TestSearcher checks that each of a Searcher's methods finds the same log
+// entries as the equivalent package level function, and that a Searcher can be
+// reused for multiple log files
+func TestSearcher(t *testing.T) {
+	StartBufLen = 256
+	searchCriteria := SearchCriteria{
+		FromTime:       parseTime(odlTimeFormat, `Jun 16, 2010 12:00:00 AM IST`),
+		LeStartPattern: odlStartPattern,
+		LeTimeFormats:  []string{odlTimeFormat},
+		LeTimeZones:    map[string]*time.Location{},
+	}
+	searcher, err := NewSearcher(&searchCriteria, nil)
+	check(err)
+
+	// changes to the search criteria after the Searcher has been created have no
+	// effect on it, including changes to its slices and maps
+	searchCriteria.LeStartPattern = ""
+	searchCriteria.LeTimeFormats[0] = time.Kitchen
+	searchCriteria.LeTimeZones["IST"] = time.FixedZone("IST", 5*60*60+30*60)
+
+	expectedCriteria := SearchCriteria{
+		FromTime:       parseTime(odlTimeFormat, `Jun 16, 2010 12:00:00 AM IST`),
+		LeStartPattern: odlStartPattern,
+		LeTimeFormat:   odlTimeFormat,
+	}
+	for _, filePath := range []string{odlLog, odlLogNoNlSuffixUnix, odlLogNoNlSuffixWin} {
+		var tests = []struct {
+			name     string
+			search   func(handler EntryHandler) (int, error)
+			expected func(outputHandler OutputHandler) (int, error)
+		}{
+			{
+				name: "ReverseSearch",
+				search: func(handler EntryHandler) (int, error) {
+					return searcher.ReverseSearch(context.Background(), filePath, handler)
+				},
+				expected: func(outputHandler OutputHandler) (int, error) {
+					return ReverseSearch(filePath, &expectedCriteria, outputHandler)
+				},
+			},
+			{
+				name: "ForwardSearch",
+				search: func(handler EntryHandler) (int, error) {
+					return searcher.ForwardSearch(filePath, handler)
+				},
+				expected: func(outputHandler OutputHandler) (int, error) {
+					return ForwardSearch(filePath, &expectedCriteria, outputHandler)
+				},
+			},
+			{
+				name: "BinarySearch",
+				search: func(handler EntryHandler) (int, error) {
+					return searcher.BinarySearch(filePath, handler)
+				},
+				expected: func(outputHandler OutputHandler) (int, error) {
+					return BinarySearch(filePath, &expectedCriteria, outputHandler)
+				},
+			},
+			{
+				name: "Search",
+				search: func(handler EntryHandler) (int, error) {
+					_, exitStatus, err := searcher.Search(filePath, handler)
+					return exitStatus, err
+				},
+				expected: func(outputHandler OutputHandler) (int, error) {
+					_, exitStatus, err := Search(filePath, &expectedCriteria, outputHandler)
+					return exitStatus, err
+				},
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(filePath+" "+test.name, func(t *testing.T) {
+				var expected, actual []string
+				expectedExitStatus, expectedErr := test.expected(
+					func(logEntry []byte) { expected = append(expected, string(logEntry)) })
+				exitStatus, err := test.search(collectEntries(&actual))
+
+				if exitStatus != expectedExitStatus || (err == nil) != (expectedErr == nil) {
+					t.Errorf("Got (%d, %v), want (%d, %v)", exitStatus, err,
+						expectedExitStatus, expectedErr)
+				}
+				if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+					t.Errorf("Got %d log entries, want %d log entries", len(actual),
+						len(expected))
+				}
+			})
+		}
+	}
+}
+
+// TestSearcherConcurrent checks that a single Searcher can search many log
+// files at once, with each search finding the same log entries as it does when
+// the log files are searched one at a time
+func TestSearcherConcurrent(t *testing.T) {
+	StartBufLen = 256
+	searcher, err := NewSearcher(&SearchCriteria{LeStartPattern: odlStartPattern},
+		&Options{StartBufLen: 64})
+	check(err)
+
+	filePaths := []string{odlLog, odlLogNoNlSuffixUnix, odlLogNoNlSuffixWin, longLineLog}
+	expected := make([][]string, len(filePaths))
+	for i, filePath := range filePaths {
+		_, err := searcher.ReverseSearch(context.Background(), filePath,
+			collectEntries(&expected[i]))
+		check(err)
+	}
+
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		for i, filePath := range filePaths {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var actual []string
+				_, err := searcher.ReverseSearch(context.Background(), filePath,
+					collectEntries(&actual))
+				if err != nil {
+					t.Error(err)
+				} else if strings.Join(actual, "\n") != strings.Join(expected[i], "\n") {
+					t.Errorf("%s: got %d log entries, want %d log entries", filePath,
+						len(actual), len(expected[i]))
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// TestSearcherConcurrentStats checks that the statistics of concurrent searches
+// by a single Searcher all add up in its Options.Stats
+func TestSearcherConcurrentStats(t *testing.T) {
+	StartBufLen = 256
+	contents := followLines[0] + "\n<Xxx 16, 2010 2:02:02 AM IST> <Warning> corrupted\n" +
+		followLines[3] + "\n"
+	stats := SearchStats{}
+	searcher, err := NewSearcher(&SearchCriteria{
+		FromTime:       parseTime(odlTimeFormat, `Jun 15, 2010 12:00:00 AM IST`),
+		LeStartPattern: odlStartPattern,
+		LeTimeFormat:   odlTimeFormat,
+	}, &Options{BadTimes: BadTimeSkip, Stats: &stats})
+	check(err)
+
+	const nSearches = 8
+	var wg sync.WaitGroup
+	for n := 0; n < nSearches; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := searcher.ReverseSearchReaderAt(context.Background(),
+				strings.NewReader(contents), int64(len(contents)),
+				func(LogEntry) (Control, error) { return Continue, nil })
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	expected := SearchStats{BadTimes: nSearches, SkippedEntries: nSearches}
+	if stats != expected {
+		t.Errorf("Got stats: %+v, want stats: %+v", stats, expected)
+	}
+}
+
+// TestNewSearcherRedPaths checks that search criteria are validated and
+// compiled when the Searcher is created
+func TestNewSearcherRedPaths(t *testing.T) {
+	var tests = []struct {
+		name           string
+		searchCriteria SearchCriteria
+		expectedErr    string
+	}{
+		{
+			name:           "test 1: no leStartPattern",
+			searchCriteria: SearchCriteria{},
+			expectedErr:    NoLeStartPattern,
+		},
+		{
+			name: "test 2: time constraint without leTimeFormat",
+			searchCriteria: SearchCriteria{
+				FromTime:       parseTime(odlTimeFormat, `Jun 16, 2010 12:00:00 AM IST`),
+				LeStartPattern: odlStartPattern,
+			},
+			expectedErr: NoLeTimeFormat,
+		},
+		{
+			name: "test 3: regexp that doesn't compile",
+			searchCriteria: SearchCriteria{
+				Regexps:        []string{`(`},
+				LeStartPattern: odlStartPattern,
+			},
+			expectedErr: BadRegexps,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			searcher, err := NewSearcher(&test.searchCriteria, nil)
+			if searcher != nil {
+				t.Errorf("Got a Searcher, want nil")
+			}
+			if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+				t.Errorf("Got error: %v, want error that contains: \"%s\"", err,
+					test.expectedErr)
+			}
+		})
+	}
+
+	// BinarySearch requires FromTime, which is only checked when it's called
+	searcher, err := NewSearcher(&SearchCriteria{LeStartPattern: odlStartPattern}, nil)
+	check(err)
+	if _, err := searcher.BinarySearch(odlLog, nil); err == nil ||
+		!strings.Contains(err.Error(), NoFromTime) {
+		t.Errorf("Got error: %v, want error that contains: \"%s\"", err, NoFromTime)
+	}
+}
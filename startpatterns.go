@@ -0,0 +1,139 @@
+package reversesearch
+
+/* The handling of log files with several log entry start patterns is contained
+in this file:
+- StartPattern (type)
+- combineStartPatterns
+- patternTimeFormats
+- lePattern (type)
+- lePatterns
+- matchedPattern
+- lePatternIndex
+
+When SearchCriteria.LeStartPatterns is set, its patterns are combined into a
+single regexp, in which each pattern is wrapped in a capturing group named
+lePatternGroup. The rest of the package can then carry on treating the start of
+a log entry as a match of a single regexp, and the pattern that matched is
+identified by the wrapping group that participated in the match.
+*/
+
+import (
+	"regexp"
+	"strings"
+)
+
+// StartPattern is one of the patterns of SearchCriteria.LeStartPatterns, for
+// log files that interleave the log entries of several writers
+type StartPattern struct {
+	// Pattern matches the beginning of the writer's log entries. It must meet
+	// the same requirements as SearchCriteria.LeStartPattern.
+	Pattern string
+
+	// TimeFormat is the layout of the time stamps captured by Pattern. If it's
+	// not set, SearchCriteria.LeTimeFormat and LeTimeFormats are used instead.
+	TimeFormat string
+}
+
+// lePatternGroup is the name of the capturing groups that wrap each of
+// LeStartPatterns in the combined regexp
+const lePatternGroup = "le_pattern"
+
+// combineStartPatterns returns the source of a regexp that matches the
+// beginning of a log entry if any of patterns does, with the first of them
+// taking precedence. Each pattern is compiled on its own first, so that a
+// pattern that only compiles as part of the combined regexp (e.g. `a)|(b`) is
+// rejected.
+func combineStartPatterns(patterns []StartPattern) (string, error) {
+	alternatives := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		if pattern.Pattern == "" {
+			return "", ErrNoLeStartPattern
+		}
+		if _, err := regexp.Compile(pattern.Pattern); err != nil {
+			return "", err
+		}
+		alternatives[i] = `(?P<` + lePatternGroup + `>` + pattern.Pattern + `)`
+	}
+	return strings.Join(alternatives, `|`), nil
+}
+
+// patternTimeFormats returns the layouts that the time stamps captured by each
+// of searchCriteria's start patterns are parsed with, i.e. a single list of
+// layouts if LeStartPattern is set, and one for each of LeStartPatterns
+// otherwise. A start pattern has no layouts if no layout applies to it.
+func patternTimeFormats(searchCriteria *SearchCriteria) [][]string {
+	if len(searchCriteria.LeStartPatterns) == 0 {
+		return [][]string{leTimeFormats(searchCriteria)}
+	}
+	formats := make([][]string, len(searchCriteria.LeStartPatterns))
+	for i, pattern := range searchCriteria.LeStartPatterns {
+		if pattern.TimeFormat != "" {
+			formats[i] = []string{pattern.TimeFormat}
+		} else {
+			formats[i] = leTimeFormats(searchCriteria)
+		}
+	}
+	return formats
+}
+
+// lePattern locates one of the start patterns within leStartRegexp
+type lePattern struct {
+	// group is the index of the capturing group that wraps the pattern, or 0 if
+	// leStartRegexp is made up of a single unwrapped pattern (LeStartPattern)
+	group int
+
+	// numSubexp is the number of the pattern's own capturing groups, which
+	// follow group
+	numSubexp int
+
+	// timeIndex is the index of the pattern's leTimeGroup capturing group, or -1
+	// if there isn't one
+	timeIndex int
+}
+
+// lePatterns returns the start patterns that leStartRegexp is made up of, in
+// order
+func lePatterns(leStartRegexp *regexp.Regexp) []lePattern {
+	var patterns []lePattern
+	for i, name := range leStartRegexp.SubexpNames() {
+		switch {
+		case i == 0 || name == lePatternGroup:
+			if i == 0 && leStartRegexp.SubexpIndex(lePatternGroup) > 0 {
+				continue // leStartRegexp is made up of wrapped patterns
+			}
+			patterns = append(patterns, lePattern{group: i, timeIndex: -1})
+		default:
+			pattern := &patterns[len(patterns)-1]
+			pattern.numSubexp++
+			if name == leTimeGroup && pattern.timeIndex < 0 {
+				pattern.timeIndex = i
+			}
+		}
+	}
+	return patterns
+}
+
+// matchedPattern returns the index of the pattern in patterns that
+// matchIndexes, the result of leStartRegexp.FindSubmatchIndex, is a match of
+func matchedPattern(patterns []lePattern, matchIndexes []int) int {
+	for i, pattern := range patterns {
+		if matchIndexes[2*pattern.group] >= 0 {
+			return i
+		}
+	}
+	return 0
+}
+
+// lePatternIndex returns the index of the start pattern (see LogEntry.Pattern)
+// that matches the first line of logEntry. 0 is returned if leStartRegexp is
+// nil, or if it's made up of a single pattern.
+func lePatternIndex(leStartRegexp *regexp.Regexp, logEntry []byte) int {
+	if leStartRegexp == nil || leStartRegexp.SubexpIndex(lePatternGroup) < 0 {
+		return 0
+	}
+	matchIndexes := leStartRegexp.FindSubmatchIndex(firstLine(logEntry))
+	if matchIndexes == nil { // sanity check
+		return 0
+	}
+	return matchedPattern(lePatterns(leStartRegexp), matchIndexes)
+}
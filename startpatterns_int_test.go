@@ -0,0 +1,190 @@
+package reversesearch_test
+
+/* Integration tests for SearchCriteria.LeStartPatterns. The log file used is
+written by the test, and interleaves application log entries with JVM GC log
+entries, each with their own header and time stamp format. */
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/freebiesoft/reversesearch"
+)
+
+// interleavedLines are the log entries of the interleaved log file
+var interleavedLines = []string{
+	"2024-05-01 12:00:00,100 INFO [main] starting",
+	"[2024-05-01T12:00:01.500+0000][gc] GC(1) Pause Young 24M->8M 2.1ms",
+	"2024-05-01 12:00:02,000 ERROR [worker-1] failed\njava.lang.IllegalStateException\n\tat Main.run",
+	"[2024-05-01T12:00:03.250+0000][gc] GC(2) Pause Young 30M->9M 1.8ms",
+	"2024-05-01 12:00:04,000 INFO [main] stopping",
+}
+
+// interleavedPatterns are the start patterns of the interleaved log file's
+// application and GC log entries
+var interleavedPatterns = []StartPattern{
+	{
+		Pattern:    `^(?P<ts>\d{4}-\d{2}-\d{2} [\d:,]+) (?P<level>[A-Z]+) \[(?P<thread>[^\]]+)\]`,
+		TimeFormat: `2006-01-02 15:04:05,000`,
+	},
+	{
+		Pattern:    `^\[([^\]]+)\]\[gc\]`,
+		TimeFormat: `2006-01-02T15:04:05.000-0700`,
+	},
+}
+
+// TestLeStartPatterns checks that log entries are delimited by any of the start
+// patterns, that each pattern's time stamps are parsed with its own layout, and
+// that log entries are tagged with the pattern that matched
+func TestLeStartPatterns(t *testing.T) {
+	StartBufLen = 64
+	logFile := filepath.Join(t.TempDir(), "interleaved.log")
+	check(ioutil.WriteFile(logFile, []byte(strings.Join(interleavedLines, "\n")+"\n"), 0644))
+	searcher, err := NewSearcher(&SearchCriteria{
+		FromTime:        time.Date(2024, time.May, 1, 12, 0, 1, 0, time.UTC),
+		UntilTime:       time.Date(2024, time.May, 1, 12, 0, 4, 0, time.UTC),
+		LeStartPatterns: interleavedPatterns,
+	}, nil)
+	check(err)
+
+	var tests = []struct {
+		name     string
+		search   func(handler EntryHandler) (int, error)
+		expected []int // indexes of the expected log entries in interleavedLines
+	}{
+		{
+			name: "test 1: reverse search",
+			search: func(handler EntryHandler) (int, error) {
+				return searcher.ReverseSearch(context.Background(), logFile, handler)
+			},
+			expected: []int{3, 2, 1},
+		},
+		{
+			name: "test 2: forward search",
+			search: func(handler EntryHandler) (int, error) {
+				return searcher.ForwardSearch(logFile, handler)
+			},
+			expected: []int{1, 2, 3},
+		},
+		{
+			name: "test 3: binary search",
+			search: func(handler EntryHandler) (int, error) {
+				return searcher.BinarySearch(logFile, handler)
+			},
+			expected: []int{1, 2, 3},
+		},
+	}
+
+	expectedPatterns := []int{0, 1, 0, 1, 0}
+	expectedFields := []map[string]string{
+		{"level": "INFO", "thread": "main"},
+		{},
+		{"level": "ERROR", "thread": "worker-1"},
+		{},
+		{"level": "INFO", "thread": "main"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var actual, expected []string
+			var patterns, wantPatterns []int
+			var fields, wantFields []map[string]string
+			_, err := test.search(func(logEntry LogEntry) (Control, error) {
+				actual = append(actual, string(logEntry.Bytes))
+				patterns = append(patterns, logEntry.Pattern)
+				fields = append(fields, logEntry.Fields)
+				return Continue, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, i := range test.expected {
+				expected = append(expected, interleavedLines[i])
+				wantPatterns = append(wantPatterns, expectedPatterns[i])
+				wantFields = append(wantFields, expectedFields[i])
+			}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("GOT:\n%q\nWANT:\n%q", actual, expected)
+			}
+			if !reflect.DeepEqual(patterns, wantPatterns) {
+				t.Errorf("Got patterns: %v, want patterns: %v", patterns, wantPatterns)
+			}
+			if !reflect.DeepEqual(fields, wantFields) {
+				t.Errorf("Got fields: %v, want fields: %v", fields, wantFields)
+			}
+		})
+	}
+}
+
+// TestLeStartPatternsErrors checks the validation of LeStartPatterns
+func TestLeStartPatternsErrors(t *testing.T) {
+	fromTime := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		name           string
+		searchCriteria SearchCriteria
+		expectedErr    error
+	}{
+		{
+			name: "test 1: both LeStartPattern and LeStartPatterns",
+			searchCriteria: SearchCriteria{
+				LeStartPattern:  odlStartPattern,
+				LeStartPatterns: interleavedPatterns,
+			},
+			expectedErr: ErrMultipleLeStartPatterns,
+		},
+		{
+			name: "test 2: empty pattern",
+			searchCriteria: SearchCriteria{
+				LeStartPatterns: []StartPattern{interleavedPatterns[0], {}},
+			},
+			expectedErr: ErrNoLeStartPattern,
+		},
+		{
+			name: "test 3: pattern that only compiles when combined",
+			searchCriteria: SearchCriteria{
+				LeStartPatterns: []StartPattern{{Pattern: `^a)|(b`}, interleavedPatterns[1]},
+			},
+			expectedErr: ErrBadLeStartPattern,
+		},
+		{
+			name: "test 4: pattern without a layout",
+			searchCriteria: SearchCriteria{
+				FromTime: fromTime,
+				LeStartPatterns: []StartPattern{
+					interleavedPatterns[0],
+					{Pattern: interleavedPatterns[1].Pattern},
+				},
+			},
+			expectedErr: ErrNoLeTimeFormat,
+		},
+		{
+			name: "test 5: pattern using LeTimeFormat",
+			searchCriteria: SearchCriteria{
+				FromTime:     fromTime,
+				LeTimeFormat: interleavedPatterns[1].TimeFormat,
+				LeStartPatterns: []StartPattern{
+					interleavedPatterns[0],
+					{Pattern: interleavedPatterns[1].Pattern},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewSearcher(&test.searchCriteria, nil)
+			if test.expectedErr == nil && err != nil {
+				t.Errorf("Got error: %v, want no error", err)
+			} else if !errors.Is(err, test.expectedErr) {
+				t.Errorf("Got error: %v, want error that is: %v", err, test.expectedErr)
+			}
+		})
+	}
+}
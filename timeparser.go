@@ -0,0 +1,385 @@
+package reversesearch
+
+/* The parsing of log entries' time stamps is contained in this file:
+- epoch time formats (e.g. EpochSeconds)
+- parseEpoch
+- leTimeFormats
+- zoneless
+- checkTimeZones
+- yearReference
+- timeParser (type)
+- newTimeParser
+- inferYear (method)
+- parseLayout (method)
+- parse (method)
+
+A timeParser is created for each search from its search criteria, so that how
+a time stamp is captured by LeStartPattern and parsed is only worked out once,
+rather than for every line.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// special values of LeTimeFormat for time stamps that are Unix epoch values
+// rather than formatted times. Each accepts an optional fractional part, e.g.
+// `1716200000.123` with EpochSeconds, and `1716200000123` with EpochMillis.
+const (
+	EpochSeconds = "epoch"
+	EpochMillis  = "epochmillis"
+	EpochMicros  = "epochmicros"
+	EpochNanos   = "epochnanos"
+)
+
+// epochUnits maps each epoch time format to the unit of its time stamps
+var epochUnits = map[string]time.Duration{
+	EpochSeconds: time.Second,
+	EpochMillis:  time.Millisecond,
+	EpochMicros:  time.Microsecond,
+	EpochNanos:   time.Nanosecond,
+}
+
+// parseEpoch parses leTimeB, a number of units since the Unix epoch with an
+// optional fractional part, into a UTC time. Fractions of a nanosecond are
+// truncated. The zero time is returned along with an error if leTimeB isn't
+// such a number.
+func parseEpoch(leTimeB []byte, unit time.Duration) (time.Time, error) {
+	intPart, fracPart := string(leTimeB), ""
+	if dotIndex := strings.IndexByte(intPart, '.'); dotIndex >= 0 {
+		intPart, fracPart = intPart[:dotIndex], intPart[dotIndex+1:]
+	}
+	n, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// frac is the fractional part in billionths of a unit
+	var frac int64
+	for i := 0; i < len(fracPart) || i < 9; i++ {
+		if i < len(fracPart) && (fracPart[i] < '0' || fracPart[i] > '9') {
+			return time.Time{}, fmt.Errorf("bad fractional part in epoch time stamp %q", leTimeB)
+		}
+		if i >= 9 {
+			continue
+		}
+		frac *= 10
+		if i < len(fracPart) {
+			frac += int64(fracPart[i] - '0')
+		}
+	}
+
+	// the fractional part takes the sign of the integer part
+	fracNsec := frac * int64(unit) / int64(time.Second)
+	if strings.HasPrefix(intPart, "-") {
+		fracNsec = -fracNsec
+	}
+	unitsPerSec := int64(time.Second / unit)
+	nsec := n%unitsPerSec*int64(unit) + fracNsec
+	return time.Unix(n/unitsPerSec, nsec).UTC(), nil
+}
+
+// leTimeFormats returns the layouts that searchCriteria's time stamps are parsed
+// with, in the order that they're tried: LeTimeFormat (if it's set), followed by
+// LeTimeFormats
+func leTimeFormats(searchCriteria *SearchCriteria) []string {
+	if searchCriteria.LeTimeFormat == "" {
+		return searchCriteria.LeTimeFormats
+	}
+	return append([]string{searchCriteria.LeTimeFormat}, searchCriteria.LeTimeFormats...)
+}
+
+// zoneless reports whether layout has no time zone (i.e. neither a zone offset
+// nor a zone abbreviation), in which case time stamps are parsed in
+// LeTimeLocation (or UTC)
+func zoneless(layout string) bool {
+	if _, ok := epochUnits[layout]; ok {
+		return false
+	}
+	probe := time.Date(2006, time.January, 2, 15, 4, 5, 0, time.FixedZone("ZZZ", 3600))
+	t, err := time.Parse(layout, probe.Format(layout))
+	return err == nil && t.Location() == time.UTC
+}
+
+// checkTimeZones checks that searchCriteria's FromTime and UntilTime are in a
+// time zone that is consistent with the log's time stamps, so that they aren't
+// silently compared hours apart. A time constraint whose zone abbreviation is in
+// LeTimeZones must have the offset that LeTimeZones gives it, and if any of
+// the layouts has no time zone and LeTimeLocation is set, a time constraint
+// must have the offset that LeTimeLocation has at that time. Time constraints
+// aren't otherwise checked, as they're compared with time stamps as instants
+// (e.g. a FromTime of time.Now().Add(-d) on a host that isn't in UTC).
+func checkTimeZones(searchCriteria *SearchCriteria) error {
+	isZoneless := false
+	for _, formats := range patternTimeFormats(searchCriteria) {
+		for _, format := range formats {
+			isZoneless = isZoneless || zoneless(format)
+		}
+	}
+	for _, constraint := range []time.Time{searchCriteria.FromTime, searchCriteria.UntilTime} {
+		if constraint.IsZero() {
+			continue
+		}
+
+		// determine the location that the log's time stamps would be in
+		name, offset := constraint.Zone()
+		location, ok := searchCriteria.LeTimeZones[name]
+		if !ok {
+			if !isZoneless || searchCriteria.LeTimeLocation == nil {
+				continue
+			}
+			location = searchCriteria.LeTimeLocation
+		}
+
+		if _, logOffset := constraint.In(location).Zone(); logOffset != offset {
+			return fmt.Errorf("%w, %s is in %s, but the log's time stamps are in %s",
+				ErrTimeZoneMismatch, constraint, name, location)
+		}
+	}
+	return nil
+}
+
+// yearReference returns the time that year-less time stamps in file are
+// anchored on (see Options.YearReference); opts.YearReference if it's set,
+// otherwise the modification time of file if it has one (e.g. an *os.File). The
+// zero time is returned if neither are available, meaning the time of parsing
+// is used instead.
+func yearReference(file io.ReaderAt, opts *Options) time.Time {
+	if !opts.YearReference.IsZero() {
+		return opts.YearReference
+	}
+	if statter, ok := file.(interface{ Stat() (os.FileInfo, error) }); ok {
+		if fileInfo, err := statter.Stat(); err == nil {
+			return fileInfo.ModTime()
+		}
+	}
+	return time.Time{}
+}
+
+// leTimeGroup is the name of the capturing group of LeStartPattern that captures
+// the time stamp, if LeStartPattern has more than one capturing group
+const leTimeGroup = "ts"
+
+// timeParser infers log entries' time of logging from the matches of
+// LeStartPattern (or LeStartPatterns) against their first lines
+type timeParser struct {
+	leStartRegexp *regexp.Regexp
+
+	// patterns are the start patterns that leStartRegexp is made up of (see
+	// lePatterns), formats are the layouts that each pattern's time stamps are
+	// parsed with (see patternTimeFormats), and last holds the index of the
+	// layout that last succeeded for each pattern, which is tried first
+	patterns []lePattern
+	formats  [][]string
+	last     []int
+
+	// template, if set, is expanded with the matches of leStartRegexp to assemble
+	// the time stamp (LeTimeTemplate)
+	template []byte
+
+	// required indicates that the time of logging is needed by the search (i.e.
+	// there are time constraints), rather than only being passed on in
+	// LogEntry.Time, so time stamps that can't be parsed are errors
+	required bool
+
+	// location is the location that time stamps without a time zone are parsed
+	// in (LeTimeLocation), and zones maps zone abbreviations to the locations
+	// they stand for (LeTimeZones)
+	location *time.Location
+	zones    map[string]*time.Location
+
+	// yearRef is the time that year-less time stamps are anchored on (the time of
+	// parsing if it's zero), and reverse indicates that time stamps are parsed in
+	// reverse order, in which case year and prevMonth hold the year inferred for
+	// the last year-less time stamp parsed (0 if there hasn't been one) and its
+	// month
+	yearRef   time.Time
+	reverse   bool
+	year      int
+	prevMonth time.Month
+}
+
+// newTimeParser returns the timeParser for searchCriteria, whose LeStartPattern
+// has been compiled to leStartRegexp. yearRef is the time that year-less time
+// stamps are anchored on (see yearReference), and reverse indicates that the
+// log file is traversed in reverse. As a timeParser keeps track of the years of
+// year-less time stamps, a new timeParser is needed for each search. nil is
+// returned if no start pattern has a layout (i.e. neither LeTimeFormat nor
+// LeTimeFormats nor any of LeStartPatterns' TimeFormat is set), as time stamps
+// are then not parsed.
+func newTimeParser(leStartRegexp *regexp.Regexp, searchCriteria *SearchCriteria,
+	yearRef time.Time, reverse bool) *timeParser {
+	formats := patternTimeFormats(searchCriteria)
+	hasFormats := false
+	for _, patternFormats := range formats {
+		hasFormats = hasFormats || len(patternFormats) > 0
+	}
+	if !hasFormats {
+		return nil
+	}
+	patterns := lePatterns(leStartRegexp)
+	required := !searchCriteria.FromTime.IsZero() || !searchCriteria.UntilTime.IsZero()
+	if len(formats) != len(patterns) { // sanity check
+		formats = append(formats, make([][]string, len(patterns))...)[:len(patterns)]
+	}
+	p := &timeParser{
+		leStartRegexp: leStartRegexp,
+		patterns:      patterns,
+		formats:       formats,
+		last:          make([]int, len(patterns)),
+		required:      required,
+		location:      searchCriteria.LeTimeLocation,
+		zones:         searchCriteria.LeTimeZones,
+		yearRef:       yearRef,
+		reverse:       reverse,
+	}
+	if searchCriteria.LeTimeTemplate != "" {
+		p.template = []byte(searchCriteria.LeTimeTemplate)
+	}
+	return p
+}
+
+// yearSkew is how far a year-less time stamp can be after the reference time
+// before it's considered to be from the previous year, which allows for time
+// stamps whose time zone is ahead of the reference time's
+const yearSkew = 24 * time.Hour
+
+// inferYear returns leTime, which was parsed with a layout that has no year
+// (i.e. its year is 0), with its year inferred. The first year-less time stamp
+// parsed, or any of them when the log file isn't traversed in reverse, is
+// given the latest year in which it isn't after the reference time (allowing
+// for yearSkew). When the log file is traversed in reverse, each subsequent
+// time stamp is given the same year as the one before it, unless its month is
+// later (e.g. December following January), in which case the year is
+// decremented.
+func (p *timeParser) inferYear(leTime time.Time) time.Time {
+	yearRef := p.yearRef
+	if yearRef.IsZero() {
+		yearRef = time.Now()
+	}
+
+	year := p.year
+	if !p.reverse || year == 0 {
+		year = yearRef.Year()
+		if withYear(leTime, year).After(yearRef.Add(yearSkew)) {
+			year--
+		}
+	} else if leTime.Month() > p.prevMonth {
+		year--
+	}
+
+	if p.reverse {
+		p.year, p.prevMonth = year, leTime.Month()
+	}
+	return withYear(leTime, year)
+}
+
+// zoneName returns the abbreviated name of t's time zone
+func zoneName(t time.Time) string {
+	name, _ := t.Zone()
+	return name
+}
+
+// withYear returns t with its year set to year
+func withYear(t time.Time, year int) time.Time {
+	return time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(),
+		t.Nanosecond(), t.Location())
+}
+
+// parseLayout parses leTimeB with format, which can be an epoch time format.
+// Time stamps without a time zone are parsed in the location if there is one
+// (and in UTC otherwise).
+func (p *timeParser) parseLayout(format string, leTimeB []byte) (time.Time, error) {
+	if epochUnit, ok := epochUnits[format]; ok {
+		return parseEpoch(leTimeB, epochUnit)
+	}
+	if p.location != nil {
+		return time.ParseInLocation(format, string(leTimeB), p.location)
+	}
+	return time.Parse(format, string(leTimeB))
+}
+
+// parse infers a log entry's time of logging from matchIndexes, the result of
+// leStartRegexp.FindSubmatchIndex against line (the first line of the log
+// entry). The time stamp is assembled from the template if there is one, and is
+// otherwise captured by the leTimeGroup capturing group of the start pattern
+// that matched, or failing that, the pattern's first (and only) capturing
+// group. The time stamp is parsed with the pattern's layout that last
+// succeeded, and failing that, its other layouts in order (see parseLayout);
+// the zero time is returned if the pattern has no layouts. A time stamp whose
+// zone abbreviation is in zones is reinterpreted in the zone's location. If the
+// layout has no year (e.g. a BSD syslog time stamp), the year is inferred (see
+// inferYear). leOffset is the position of the log entry in the log file, which
+// is reported in a *TimeParseError if none of the layouts match.
+func (p *timeParser) parse(line []byte, matchIndexes []int, leOffset int64) (time.Time, error) {
+	if len(matchIndexes) == 0 { // sanity check
+		return time.Time{}, errors.New(`matches is empty`)
+	}
+
+	patternIndex := matchedPattern(p.patterns, matchIndexes)
+	pattern, formats := p.patterns[patternIndex], p.formats[patternIndex]
+	if len(formats) == 0 { // the pattern's time stamps aren't parsed
+		return time.Time{}, nil
+	}
+
+	// retrieve the log entry's time stamp
+	var leTimeB []byte
+	switch {
+	case p.template != nil:
+		leTimeB = p.leStartRegexp.Expand(nil, p.template, line, matchIndexes)
+	case pattern.timeIndex < 0 && pattern.numSubexp < 1:
+		return time.Time{}, fmt.Errorf("%w, a capturing group is needed to identify log time",
+			ErrLeStartPatternBadlyFormed)
+	case pattern.timeIndex < 0 && pattern.numSubexp > 1:
+		return time.Time{}, fmt.Errorf("%w, there should only be one capturing group to "+
+			"identify log time, unless it is named %q", ErrLeStartPatternBadlyFormed, leTimeGroup)
+	default:
+		timeIndex := pattern.timeIndex
+		if timeIndex < 0 {
+			timeIndex = pattern.group + 1
+		}
+		if start := matchIndexes[2*timeIndex]; start >= 0 {
+			leTimeB = line[start:matchIndexes[2*timeIndex+1]]
+		}
+	}
+
+	// create Time struct that represents log entry's time of logging
+	last := p.last[patternIndex]
+	leTime, err := p.parseLayout(formats[last], leTimeB)
+	for i := 0; err != nil && i < len(formats); i++ {
+		if i == last {
+			continue
+		}
+		var layoutErr error
+		if leTime, layoutErr = p.parseLayout(formats[i], leTimeB); layoutErr == nil {
+			p.last[patternIndex], err = i, nil
+		}
+	}
+	if err != nil { // none of the layouts match
+		// if time constraints exist, it must be possible to infer the log entry's
+		// time of logging, so an error must be returned
+		return time.Time{}, &TimeParseError{
+			Bytes:  append([]byte(nil), leTimeB...),
+			Offset: leOffset,
+			Err:    err,
+		}
+	}
+	if location, ok := p.zones[zoneName(leTime)]; ok {
+		// the zone abbreviation stands for location, so reinterpret the time of
+		// logging in it
+		leTime = time.Date(leTime.Year(), leTime.Month(), leTime.Day(), leTime.Hour(),
+			leTime.Minute(), leTime.Second(), leTime.Nanosecond(), location)
+	}
+	if leTime.Year() == 0 { // the layout has no year
+		leTime = p.inferYear(leTime)
+	}
+
+	return leTime, nil
+}
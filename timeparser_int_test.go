@@ -0,0 +1,354 @@
+package reversesearch_test
+
+/* Integration tests for the ways in which log entries' time stamps can be
+parsed, other than with a single time.Parse layout, for the time zones that
+they're parsed in, and for the inference of the years of time stamps that don't
+have one. */
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/freebiesoft/reversesearch"
+)
+
+// TestEpochTimeFormats checks that log entries stamped with Unix epoch values
+// are compared with the time constraints
+func TestEpochTimeFormats(t *testing.T) {
+	StartBufLen = 256
+	fromTime := time.Unix(1716200000, 500000000)
+	untilTime := time.Unix(1716200002, 0)
+
+	var tests = []struct {
+		name         string
+		leTimeFormat string
+		timeStamps   []string // time stamps of the log entries in the log file
+		expected     []int    // indexes of the expected log entries
+	}{
+		{
+			name:         "test 1: fractional seconds",
+			leTimeFormat: EpochSeconds,
+			timeStamps:   []string{"1716199999.999", "1716200000.5", "1716200001.25", "1716200002"},
+			expected:     []int{2, 1},
+		},
+		{
+			name:         "test 2: milliseconds",
+			leTimeFormat: EpochMillis,
+			timeStamps:   []string{"1716200000499", "1716200000500", "1716200001999", "1716200002000"},
+			expected:     []int{2, 1},
+		},
+		{
+			name:         "test 3: microseconds",
+			leTimeFormat: EpochMicros,
+			timeStamps:   []string{"1716200000499999", "1716200000500000", "1716200002000000"},
+			expected:     []int{1},
+		},
+		{
+			name:         "test 4: nanoseconds",
+			leTimeFormat: EpochNanos,
+			timeStamps:   []string{"1716200000499999999", "1716200001999999999"},
+			expected:     []int{1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var lines []string
+			for i, timeStamp := range test.timeStamps {
+				lines = append(lines, timeStamp+" INFO entry "+string(rune('a'+i)))
+			}
+			logFile := filepath.Join(t.TempDir(), "epoch.log")
+			check(ioutil.WriteFile(logFile, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+
+			var actual []string
+			_, err := ReverseSearchWithOptions(context.Background(), logFile, &SearchCriteria{
+				FromTime:       fromTime,
+				UntilTime:      untilTime,
+				LeStartPattern: `^([\d.]+) `,
+				LeTimeFormat:   test.leTimeFormat,
+			}, nil, collectEntries(&actual))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := []string{}
+			for _, i := range test.expected {
+				expected = append(expected, lines[i])
+			}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("GOT:\n%q\nWANT:\n%q", actual, expected)
+			}
+		})
+	}
+}
+
+// TestYearInference checks that the years of syslog style time stamps are
+// inferred from the reference time, and from the order of the log entries when
+// the log file is searched in reverse
+func TestYearInference(t *testing.T) {
+	StartBufLen = 256
+	syslogStartPattern := `^(\w{3} [ \d]\d \d{2}:\d{2}:\d{2}) `
+	syslogTimeFormat := `Jan _2 15:04:05`
+	date := func(year int, month time.Month, day int) string {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
+	}
+
+	var tests = []struct {
+		name          string
+		lines         []string
+		modTime       time.Time // the log file's modification time
+		yearReference time.Time
+		forward       bool
+		expectedTimes []string // oldest first
+	}{
+		{
+			name:          "test 1: year rollover, anchored on the modification time",
+			lines:         []string{"Dec 31 00:00:00 host a", "Jan  1 00:00:00 host b"},
+			modTime:       time.Date(2025, time.January, 3, 0, 0, 0, 0, time.UTC),
+			expectedTimes: []string{date(2024, time.December, 31), date(2025, time.January, 1)},
+		},
+		{
+			name:          "test 2: year rollover, anchored on YearReference",
+			lines:         []string{"Dec 31 00:00:00 host a", "Jan  1 00:00:00 host b"},
+			modTime:       time.Date(2030, time.January, 3, 0, 0, 0, 0, time.UTC),
+			yearReference: time.Date(2025, time.January, 3, 0, 0, 0, 0, time.UTC),
+			expectedTimes: []string{date(2024, time.December, 31), date(2025, time.January, 1)},
+		},
+		{
+			name:          "test 3: newest log entry is ahead of the reference time's time zone",
+			lines:         []string{"Dec 30 00:00:00 host a", "Dec 31 10:00:00 host b"},
+			modTime:       time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC),
+			expectedTimes: []string{date(2024, time.December, 30), "2024-12-31T10:00:00Z"},
+		},
+		{
+			name: "test 4: log file spanning more than a year",
+			lines: []string{"Mar  1 00:00:00 host a", "Dec  1 00:00:00 host b",
+				"Jun  1 00:00:00 host c"},
+			modTime: time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+			expectedTimes: []string{date(2024, time.March, 1), date(2024, time.December, 1),
+				date(2025, time.June, 1)},
+		},
+		{
+			name:          "test 5: forward search",
+			lines:         []string{"Dec 31 00:00:00 host a", "Jan  1 00:00:00 host b"},
+			modTime:       time.Date(2025, time.January, 3, 0, 0, 0, 0, time.UTC),
+			forward:       true,
+			expectedTimes: []string{date(2024, time.December, 31), date(2025, time.January, 1)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logFile := filepath.Join(t.TempDir(), "syslog")
+			check(ioutil.WriteFile(logFile, []byte(strings.Join(test.lines, "\n")+"\n"), 0644))
+			check(os.Chtimes(logFile, test.modTime, test.modTime))
+
+			searcher, err := NewSearcher(&SearchCriteria{
+				FromTime:       time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+				LeStartPattern: syslogStartPattern,
+				LeTimeFormat:   syslogTimeFormat,
+			}, &Options{YearReference: test.yearReference})
+			check(err)
+
+			var times []string
+			handler := func(logEntry LogEntry) (Control, error) {
+				times = append(times, logEntry.Time.Format(time.RFC3339))
+				return Continue, nil
+			}
+			if test.forward {
+				_, err = searcher.ForwardSearch(logFile, handler)
+			} else {
+				_, err = searcher.ReverseSearch(context.Background(), logFile, handler)
+				// reverse the times, so that they're oldest first
+				for i, j := 0, len(times)-1; i < j; i, j = i+1, j-1 {
+					times[i], times[j] = times[j], times[i]
+				}
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(times, test.expectedTimes) {
+				t.Errorf("Got times: %q, want times: %q", times, test.expectedTimes)
+			}
+		})
+	}
+}
+
+// TestLeTimeFormats checks that a log file whose time stamp format changes part
+// way through can be searched with time constraints spanning the change
+func TestLeTimeFormats(t *testing.T) {
+	StartBufLen = 256
+	logFile := filepath.Join(t.TempDir(), "upgraded.log")
+	lines := []string{
+		"[01/May/2024:10:00:00 +0000] INFO a",
+		"[01/May/2024:11:00:00 +0000] INFO b",
+		"[2024-05-01T12:00:00.125Z] INFO upgraded",
+		"[2024-05-01T13:00:00.500Z] INFO c",
+	}
+	check(ioutil.WriteFile(logFile, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+	searchCriteria := SearchCriteria{
+		FromTime:       time.Date(2024, time.May, 1, 10, 30, 0, 0, time.UTC),
+		UntilTime:      time.Date(2024, time.May, 1, 13, 0, 0, 0, time.UTC),
+		LeStartPattern: `^\[([^\]]+)\]`,
+		LeTimeFormats:  []string{`02/Jan/2006:15:04:05 -0700`, `2006-01-02T15:04:05.000Z07:00`},
+	}
+	searcher, err := NewSearcher(&searchCriteria, nil)
+	check(err)
+
+	var tests = []struct {
+		name     string
+		search   func(handler EntryHandler) (int, error)
+		expected []string
+	}{
+		{
+			name: "test 1: reverse search",
+			search: func(handler EntryHandler) (int, error) {
+				return searcher.ReverseSearch(context.Background(), logFile, handler)
+			},
+			expected: []string{lines[2], lines[1]},
+		},
+		{
+			name: "test 2: forward search",
+			search: func(handler EntryHandler) (int, error) {
+				return searcher.ForwardSearch(logFile, handler)
+			},
+			expected: lines[1:3],
+		},
+		{
+			name: "test 3: binary search",
+			search: func(handler EntryHandler) (int, error) {
+				return searcher.BinarySearch(logFile, handler)
+			},
+			expected: lines[1:3],
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var actual []string
+			if _, err := test.search(collectEntries(&actual)); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("GOT:\n%q\nWANT:\n%q", actual, test.expected)
+			}
+		})
+	}
+}
+
+// TestTimeZones checks that zone-less time stamps are parsed in LeTimeLocation,
+// that zone abbreviations are resolved with LeTimeZones, and that time
+// constraints in an inconsistent time zone are rejected
+func TestTimeZones(t *testing.T) {
+	StartBufLen = 256
+	cest := time.FixedZone("CEST", 2*60*60)
+	ist := time.FixedZone("IST", 5*60*60+30*60)
+	zonelessTimeFormat := `2006-01-02 15:04:05`
+
+	var tests = []struct {
+		name           string
+		lines          []string
+		searchCriteria SearchCriteria
+		expectedTimes  []string
+		expectedErr    error
+	}{
+		{
+			name: "test 1: zone-less time stamps in LeTimeLocation",
+			lines: []string{
+				"2024-05-01 10:00:00 a",
+				"2024-05-01 11:00:00 b",
+				"2024-05-01 12:00:00 c",
+			},
+			searchCriteria: SearchCriteria{
+				FromTime:       time.Date(2024, time.May, 1, 11, 0, 0, 0, cest),
+				LeStartPattern: `^(\S+ \S+) `,
+				LeTimeFormat:   zonelessTimeFormat,
+				LeTimeLocation: cest,
+			},
+			expectedTimes: []string{"2024-05-01T11:00:00+02:00", "2024-05-01T12:00:00+02:00"},
+		},
+		{
+			name: "test 2: zone abbreviations resolved with LeTimeZones",
+			lines: []string{
+				"<Jun 15, 2010 2:00:00 AM IST> <Info> a",
+				"<Jun 16, 2010 2:00:00 AM IST> <Info> b",
+			},
+			searchCriteria: SearchCriteria{
+				FromTime:       time.Date(2010, time.June, 15, 20, 0, 0, 0, time.UTC),
+				LeStartPattern: odlStartPattern,
+				LeTimeFormat:   odlTimeFormat,
+				LeTimeZones:    map[string]*time.Location{"IST": ist},
+			},
+			expectedTimes: []string{"2010-06-16T02:00:00+05:30"},
+		},
+		{
+			name: "test 3: zone-less time stamps in UTC compared with a time constraint in another zone",
+			lines: []string{
+				"2024-05-01 08:00:00 a",
+				"2024-05-01 09:00:00 b",
+				"2024-05-01 10:00:00 c",
+			},
+			searchCriteria: SearchCriteria{
+				FromTime:       time.Date(2024, time.May, 1, 11, 0, 0, 0, cest),
+				LeStartPattern: `^(\S+ \S+) `,
+				LeTimeFormat:   zonelessTimeFormat,
+			},
+			expectedTimes: []string{"2024-05-01T09:00:00Z", "2024-05-01T10:00:00Z"},
+		},
+		{
+			name: "test 4: time constraint with an abbreviation that LeTimeZones defines differently",
+			searchCriteria: SearchCriteria{
+				UntilTime:      time.Date(2010, time.June, 16, 0, 0, 0, 0, time.FixedZone("IST", 0)),
+				LeStartPattern: odlStartPattern,
+				LeTimeFormat:   odlTimeFormat,
+				LeTimeZones:    map[string]*time.Location{"IST": ist},
+			},
+			expectedErr: ErrTimeZoneMismatch,
+		},
+		{
+			name: "test 5: time constraint in a different zone to LeTimeLocation",
+			searchCriteria: SearchCriteria{
+				FromTime:       time.Date(2024, time.May, 1, 9, 0, 0, 0, time.UTC),
+				LeStartPattern: `^(\S+ \S+) `,
+				LeTimeFormat:   zonelessTimeFormat,
+				LeTimeLocation: cest,
+			},
+			expectedErr: ErrTimeZoneMismatch,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			searcher, err := NewSearcher(&test.searchCriteria, nil)
+			if test.expectedErr != nil {
+				if !errors.Is(err, test.expectedErr) {
+					t.Errorf("Got error: %v, want error that is: %v", err, test.expectedErr)
+				}
+				return
+			}
+			check(err)
+
+			logFile := filepath.Join(t.TempDir(), "zones.log")
+			check(ioutil.WriteFile(logFile, []byte(strings.Join(test.lines, "\n")+"\n"), 0644))
+			var times []string
+			_, err = searcher.ForwardSearch(logFile, func(logEntry LogEntry) (Control, error) {
+				times = append(times, logEntry.Time.Format(time.RFC3339))
+				return Continue, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(times, test.expectedTimes) {
+				t.Errorf("Got times: %q, want times: %q", times, test.expectedTimes)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package reversesearch_test
+
+/* Integration tests for SearchCriteria.Tolerance. The log file used is written
+by the test, with log entries whose time stamps are out of chronological order
+by up to 15 minutes. */
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/freebiesoft/reversesearch"
+)
+
+// toleranceLines are ODL log entries, some of which are out of chronological
+// order
+var toleranceLines = []string{
+	"<Jun 15, 2010 10:00:00 AM IST> <Info> a",
+	"<Jun 15, 2010 10:20:00 AM IST> <Info> b",
+	"<Jun 15, 2010 10:10:00 AM IST> <Info> c",
+	"<Jun 15, 2010 10:30:00 AM IST> <Info> d",
+	"<Jun 15, 2010 10:22:00 AM IST> <Info> e",
+	"<Jun 15, 2010 10:40:00 AM IST> <Info> f",
+}
+
+// TestTolerance checks that the abort mechanism allows for log entries that are
+// out of order by up to Tolerance, while log entries outside of the time
+// constraints are still filtered out
+func TestTolerance(t *testing.T) {
+	StartBufLen = 256
+	logFile := filepath.Join(t.TempDir(), "tolerance.log")
+	check(ioutil.WriteFile(logFile, []byte(strings.Join(toleranceLines, "\n")+"\n"), 0644))
+	fromTime := parseTime(odlTimeFormat, `Jun 15, 2010 10:30:00 AM IST`)
+	untilTime := parseTime(odlTimeFormat, `Jun 15, 2010 10:25:00 AM IST`)
+
+	var tests = []struct {
+		name            string
+		search          func(searchCriteria *SearchCriteria, handler EntryHandler) (int, error)
+		searchCriteria  SearchCriteria
+		expectedEntries []int // indexes of the expected log entries in toleranceLines
+	}{
+		{
+			name: "test 1: reverse search without tolerance",
+			searchCriteria: SearchCriteria{
+				FromTime: fromTime,
+			},
+			expectedEntries: []int{5},
+		},
+		{
+			name: "test 2: reverse search with tolerance",
+			searchCriteria: SearchCriteria{
+				FromTime:  fromTime,
+				Tolerance: 10 * time.Minute,
+			},
+			expectedEntries: []int{5, 3},
+		},
+		{
+			name: "test 3: reverse search with tolerance reaching the first log entry",
+			searchCriteria: SearchCriteria{
+				FromTime:  fromTime,
+				Tolerance: time.Hour,
+			},
+			expectedEntries: []int{5, 3},
+		},
+		{
+			name: "test 4: forward search without tolerance",
+			search: func(searchCriteria *SearchCriteria, handler EntryHandler) (int, error) {
+				searcher, err := NewSearcher(searchCriteria, nil)
+				check(err)
+				return searcher.ForwardSearch(logFile, handler)
+			},
+			searchCriteria: SearchCriteria{
+				UntilTime: untilTime,
+			},
+			expectedEntries: []int{0, 1, 2},
+		},
+		{
+			name: "test 5: forward search with tolerance",
+			search: func(searchCriteria *SearchCriteria, handler EntryHandler) (int, error) {
+				searcher, err := NewSearcher(searchCriteria, nil)
+				check(err)
+				return searcher.ForwardSearch(logFile, handler)
+			},
+			searchCriteria: SearchCriteria{
+				UntilTime: untilTime,
+				Tolerance: 10 * time.Minute,
+			},
+			expectedEntries: []int{0, 1, 2, 4},
+		},
+		{
+			name: "test 6: binary search with tolerance",
+			search: func(searchCriteria *SearchCriteria, handler EntryHandler) (int, error) {
+				searcher, err := NewSearcher(searchCriteria, &Options{StartBufLen: 64})
+				check(err)
+				return searcher.BinarySearch(logFile, handler)
+			},
+			searchCriteria: SearchCriteria{
+				FromTime:  parseTime(odlTimeFormat, `Jun 15, 2010 10:21:00 AM IST`),
+				UntilTime: parseTime(odlTimeFormat, `Jun 15, 2010 10:35:00 AM IST`),
+				Tolerance: 15 * time.Minute,
+			},
+			expectedEntries: []int{3, 4},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.searchCriteria.LeStartPattern = odlStartPattern
+			test.searchCriteria.LeTimeFormat = odlTimeFormat
+			if test.search == nil {
+				test.search = func(searchCriteria *SearchCriteria,
+					handler EntryHandler) (int, error) {
+					return ReverseSearchWithOptions(context.Background(), logFile,
+						searchCriteria, nil, handler)
+				}
+			}
+
+			var actual []string
+			exitStatus, err := test.search(&test.searchCriteria, collectEntries(&actual))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exitStatus != 0 {
+				t.Errorf("Got exit status: %d, want exit status: 0", exitStatus)
+			}
+
+			expected := []string{}
+			for _, i := range test.expectedEntries {
+				expected = append(expected, toleranceLines[i])
+			}
+			if actual == nil {
+				actual = []string{}
+			}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("GOT:\n%q\nWANT:\n%q", actual, expected)
+			}
+		})
+	}
+
+	// a negative tolerance is invalid
+	_, err := NewSearcher(&SearchCriteria{
+		FromTime:       fromTime,
+		LeStartPattern: odlStartPattern,
+		LeTimeFormat:   odlTimeFormat,
+		Tolerance:      -time.Minute,
+	}, nil)
+	if !errors.Is(err, ErrNegativeTolerance) {
+		t.Errorf("Got error: %v, want error that is: %v", err, ErrNegativeTolerance)
+	}
+}